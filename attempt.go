@@ -0,0 +1,48 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// Attempt identifies one try of a logical request made by the retry transport.
+type Attempt struct {
+	Number int // 1 for the first try
+	Max    int // Upper bound of tries for the logical request
+}
+
+type attemptKey struct{}
+
+type attemptCounterKey struct{}
+
+// AttemptFromContext returns the attempt the retry transport is making, when there is one.
+func AttemptFromContext(ctx context.Context) (Attempt, bool) {
+	attempt, ok := ctx.Value(attemptKey{}).(Attempt)
+	return attempt, ok
+}
+
+func contextWithAttempt(ctx context.Context, attempt Attempt) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// attemptCounter lets a layer sitting outside the retry transport learn how many tries were made.
+type attemptCounter struct {
+	n atomic.Int64
+}
+
+func attemptCounterFromContext(ctx context.Context) *attemptCounter {
+	counter, _ := ctx.Value(attemptCounterKey{}).(*attemptCounter)
+	return counter
+}
+
+// withAttemptCounter returns a copy of req carrying a fresh attempt counter.
+func withAttemptCounter(req *http.Request) (*http.Request, *attemptCounter) {
+	counter := &attemptCounter{}
+	return req.WithContext(context.WithValue(req.Context(), attemptCounterKey{}, counter)), counter
+}
+
+// attempts returns the tries counted so far, at least 1 once the request was sent.
+func (c *attemptCounter) attempts() int {
+	return max(int(c.n.Load()), 1)
+}
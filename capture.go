@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+)
+
+// captureRedactSlack is how many bytes are captured past a body size limit, so redaction patterns
+// still match values crossing the cut.
+const captureRedactSlack = 256
+
+// captureLimit returns the capture limit of bodies shown up to maxBodySize, 0 meaning unlimited.
+func captureLimit(maxBodySize int) int {
+	if maxBodySize == 0 {
+		return 0
+	}
+
+	return maxBodySize + captureRedactSlack
+}
+
+// bodyCapture passes a body through while keeping its first limit bytes and counting the total read.
+// A zero limit keeps the whole body; a negative limit keeps nothing.
+type bodyCapture struct {
+	rc      io.ReadCloser
+	limit   int
+	onClose func(*bodyCapture)
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	n    int64
+	once sync.Once
+}
+
+func newBodyCapture(rc io.ReadCloser, limit int, onClose func(*bodyCapture)) *bodyCapture {
+	return &bodyCapture{rc: rc, limit: limit, onClose: onClose}
+}
+
+func (c *bodyCapture) Read(p []byte) (int, error) {
+	n, err := c.rc.Read(p)
+	if n > 0 {
+		c.mu.Lock()
+		c.n += int64(n)
+		if keep := c.room(); keep > 0 {
+			c.buf.Write(p[:min(n, keep)])
+		}
+		c.mu.Unlock()
+	}
+
+	return n, err
+}
+
+func (c *bodyCapture) Close() error {
+	err := c.rc.Close()
+	if c.onClose != nil {
+		c.once.Do(func() { c.onClose(c) })
+	}
+
+	return err
+}
+
+// room returns how many more bytes may be kept.
+func (c *bodyCapture) room() int {
+	switch {
+	case c.limit < 0:
+		return 0
+	case c.limit == 0:
+		return int(^uint(0) >> 1)
+	default:
+		return c.limit - c.buf.Len()
+	}
+}
+
+// captured returns a copy of the kept bytes and whether more bytes than that were read.
+func (c *bodyCapture) captured() ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return bytes.Clone(c.buf.Bytes()), c.n > int64(c.buf.Len())
+}
+
+// bytesRead returns the total number of bytes read through the capture.
+func (c *bodyCapture) bytesRead() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// preparedBody is a captured body decompressed, redacted and cut for display.
+type preparedBody struct {
+	data      []byte // Redacted text, or the raw bytes of a binary body
+	binary    bool   // Binary bodies are not redacted
+	truncated bool   // More bytes were read than data holds
+	size      int64  // Decoded size of a complete body, bytes read otherwise
+}
+
+// prepareBody decompresses what capture kept according to header, redacts it unless it is binary and
+// cuts it at maxBodySize, 0 meaning unlimited.
+func prepareBody(capture *bodyCapture, header http.Header, r *redactor, maxBodySize int) preparedBody {
+	body, truncated := capture.captured()
+	body, truncated = decompressBody(header.Get("Content-Encoding"), body, truncated)
+
+	prepared := preparedBody{size: int64(len(body))}
+	if truncated {
+		prepared.size = capture.bytesRead()
+	}
+
+	contentType := header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	prepared.binary = (mediaType != "" && classifyMediaType(mediaType) == bodyKindBinary) || !utf8.Valid(body)
+	if !prepared.binary {
+		body = r.redactBody(contentType, body)
+	}
+
+	if maxBodySize != 0 && len(body) > maxBodySize {
+		body, truncated = body[:maxBodySize], true
+	}
+
+	prepared.data, prepared.truncated = body, truncated
+
+	return prepared
+}
@@ -0,0 +1,179 @@
+// Package cassette records HTTP interactions to a file and replays them in tests, VCR style.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version of the cassette file format.
+const Version = 1
+
+const encodingBase64 = "base64"
+
+var (
+	// ErrInteractionNotFound is returned in replay mode for requests no recorded interaction matches.
+	ErrInteractionNotFound = errors.New("cassette: no interaction matches the request")
+	// ErrCassetteNotFound is returned by NewTransport in replay mode when the cassette file is missing.
+	ErrCassetteNotFound = errors.New("cassette: file not found")
+)
+
+// Cassette is the content of a cassette file, YAML for .yaml and .yml paths and JSON otherwise.
+type Cassette struct {
+	Version      int           `json:"version" yaml:"version"`
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request" yaml:"request"`
+	Response Response `json:"response" yaml:"response"`
+}
+
+// Request is a recorded request, redacted as configured when it was written.
+type Request struct {
+	Method       string              `json:"method" yaml:"method"`
+	URL          string              `json:"url" yaml:"url"`
+	Headers      map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body         string              `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyEncoding string              `json:"body_encoding,omitempty" yaml:"body_encoding,omitempty"` // base64 for binary bodies
+}
+
+// Response is a recorded response, or the error the transport returned instead when Error is set.
+type Response struct {
+	StatusCode   int                 `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	Headers      map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body         string              `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyEncoding string              `json:"body_encoding,omitempty" yaml:"body_encoding,omitempty"` // base64 for binary bodies
+	Error        string              `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrCassetteNotFound, path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cassette
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &c)
+	} else {
+		err = json.Unmarshal(data, &c)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cassette: decode %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to path, replacing the file atomically.
+func (c *Cassette) Save(path string) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if isYAML(path) {
+		data, err = yaml.Marshal(c)
+	} else {
+		data, err = json.MarshalIndent(c, "", "  ")
+		data = append(data, '\n')
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// encodeBody stores text bodies as is and binary ones in base64.
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), encodingBase64
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+	if encoding == encodingBase64 {
+		return base64.StdEncoding.DecodeString(body)
+	}
+
+	return []byte(body), nil
+}
+
+// bytes returns the decoded body of a recorded request.
+func (r Request) bytes() []byte {
+	body, err := decodeBody(r.Body, r.BodyEncoding)
+	if err != nil {
+		return []byte(r.Body)
+	}
+
+	return body
+}
+
+// header returns the recorded headers as an http.Header.
+func header(headers map[string][]string) http.Header {
+	h := make(http.Header, len(headers))
+	for key, values := range headers {
+		h[http.CanonicalHeaderKey(key)] = values
+	}
+
+	return h
+}
+
+// equalJSON reports whether a and b are both JSON and hold the same value.
+func equalJSON(a, b []byte) (bool, bool) {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false, false
+	}
+
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+
+	return bytes.Equal(ja, jb), true
+}
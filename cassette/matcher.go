@@ -0,0 +1,73 @@
+package cassette
+
+import (
+	"bytes"
+	"net/url"
+	"slices"
+)
+
+// Matcher reports whether an incoming request, redacted like recorded ones, matches a recorded request.
+type Matcher func(incoming, recorded Request) bool
+
+// DefaultMatchers match on method, URL without query and normalized query.
+var DefaultMatchers = []Matcher{MatchMethod, MatchURL, MatchQuery}
+
+func MatchMethod(incoming, recorded Request) bool {
+	return incoming.Method == recorded.Method
+}
+
+// MatchURL compares scheme, host and path, ignoring the query and fragment.
+func MatchURL(incoming, recorded Request) bool {
+	a, errA := url.Parse(incoming.URL)
+	b, errB := url.Parse(recorded.URL)
+	if errA != nil || errB != nil {
+		return incoming.URL == recorded.URL
+	}
+
+	return a.Scheme == b.Scheme && a.Host == b.Host && a.EscapedPath() == b.EscapedPath()
+}
+
+// MatchQuery compares query parameters regardless of their order.
+func MatchQuery(incoming, recorded Request) bool {
+	a, errA := url.Parse(incoming.URL)
+	b, errB := url.Parse(recorded.URL)
+	if errA != nil || errB != nil {
+		return incoming.URL == recorded.URL
+	}
+
+	return a.Query().Encode() == b.Query().Encode()
+}
+
+// MatchHeaders returns a Matcher comparing the values of the named headers.
+func MatchHeaders(names ...string) Matcher {
+	return func(incoming, recorded Request) bool {
+		a, b := header(incoming.Headers), header(recorded.Headers)
+		for _, name := range names {
+			if !slices.Equal(a.Values(name), b.Values(name)) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// MatchBody compares bodies, as values when both are JSON and byte for byte otherwise.
+func MatchBody(incoming, recorded Request) bool {
+	a, b := incoming.bytes(), recorded.bytes()
+	if equal, ok := equalJSON(a, b); ok {
+		return equal
+	}
+
+	return bytes.Equal(a, b)
+}
+
+func matches(matchers []Matcher, incoming, recorded Request) bool {
+	for _, match := range matchers {
+		if !match(incoming, recorded) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,59 @@
+package cassette
+
+import (
+	"log/slog"
+
+	"github.com/dangnmh/transport"
+)
+
+type Option func(*config) *config
+
+// OptionMode selects between replaying, recording, or replaying and recording what is missing.
+func OptionMode(mode Mode) Option {
+	return func(c *config) *config {
+		c.mode = mode
+		return c
+	}
+}
+
+// OptionMatchers replaces DefaultMatchers, a recorded interaction is replayed when all matchers agree.
+func OptionMatchers(matchers ...Matcher) Option {
+	return func(c *config) *config {
+		c.matchers = matchers
+		return c
+	}
+}
+
+// OptionMatcherConfig limits recording to matching paths and statuses, other requests pass through.
+func OptionMatcherConfig(matcherConfig transport.MatcherConfig) Option {
+	return func(c *config) *config {
+		c.MatcherConfig = matcherConfig
+		return c
+	}
+}
+
+// OptionMatcher selects the requests going through the cassette with matcher, built with combinators
+// such as transport.And, instead of MatcherConfig. Unlike OptionMatchers, it does not pick the
+// interaction to replay.
+func OptionMatcher(matcher transport.Matcher) Option {
+	return func(c *config) *config {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// OptionLogger sets the logger warning about invalid MatcherConfig patterns, slog.Default() by default.
+func OptionLogger(logger *slog.Logger) Option {
+	return func(c *config) *config {
+		c.logger = logger
+		return c
+	}
+}
+
+// OptionRedactConfig replaces the redaction rules applied before interactions are written.
+func OptionRedactConfig(redactConfig transport.RedactConfig) Option {
+	return func(c *config) *config {
+		c.RedactConfig = redactConfig
+		return c
+	}
+}
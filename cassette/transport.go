@@ -0,0 +1,264 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/dangnmh/transport"
+)
+
+// Mode selects whether requests are sent, replayed, or both.
+type Mode int
+
+// layer names the transport in its warnings.
+const layer = "cassette"
+
+const (
+	ModeReplay         Mode = iota // Serve every request from the cassette, failing unmatched ones
+	ModeRecord                     // Send every request and record it, replacing the cassette
+	ModeReplayOrRecord             // Serve matched requests from the cassette, send and record the others
+)
+
+// Transport records interactions to a cassette file or replays them from it.
+type Transport struct {
+	tp       http.RoundTripper
+	path     string
+	config   *config
+	matcher  transport.Matcher
+	redactor *transport.Redactor
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+type config struct {
+	transport.MatcherConfig
+	transport.RedactConfig
+	matcher  transport.Matcher // Replaces MatcherConfig when set
+	logger   *slog.Logger      // Receives the warnings about invalid MatcherConfig patterns
+	mode     Mode
+	matchers []Matcher
+}
+
+var DefaultConfig = config{
+	MatcherConfig: transport.MatcherConfig{
+		OnStatus:       []int{transport.NumberZero},
+		WhiteListPaths: []string{transport.ConsCharStar},
+	},
+	RedactConfig: transport.DefaultRedactConfig,
+	logger:       slog.Default(),
+	mode:         ModeReplay,
+	matchers:     DefaultMatchers,
+}
+
+// NewTransport loads the cassette at path, required in ModeReplay, and returns a transport recording
+// to it through tp or replaying from it. Recorded interactions are written to path as they happen,
+// redacted with the configured RedactConfig.
+func NewTransport(tp http.RoundTripper, path string, opts ...Option) (*Transport, error) {
+	cfg := DefaultConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	t := &Transport{
+		tp:       tp,
+		path:     path,
+		config:   &cfg,
+		matcher:  transport.MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, cfg.logger, layer),
+		redactor: transport.NewRedactor(cfg.RedactConfig),
+		cassette: &Cassette{Version: Version},
+	}
+	if cfg.mode == ModeRecord {
+		return t, nil
+	}
+
+	c, err := Load(path)
+	if errors.Is(err, ErrCassetteNotFound) && cfg.mode == ModeReplayOrRecord {
+		return t, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.cassette = c
+	t.used = make([]bool, len(c.Interactions))
+
+	return t, nil
+}
+
+// Cassette returns a copy of the interactions recorded or loaded so far.
+func (t *Transport) Cassette() Cassette {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := *t.cassette
+	c.Interactions = append([]Interaction(nil), c.Interactions...)
+	return c
+}
+
+// RoundTrip passes requests MatcherConfig does not match through to the wrapped transport.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.matcher.MatchPath(req) {
+		return t.tp.RoundTrip(req)
+	}
+
+	req, body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	incoming := t.request(req, body)
+	if t.config.mode != ModeRecord {
+		if interaction, ok := t.find(incoming); ok {
+			return replay(req, interaction.Response)
+		}
+
+		if t.config.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, incoming.Method, incoming.URL)
+		}
+	}
+
+	return t.record(req, incoming)
+}
+
+// find returns the first unused interaction matching incoming. Once all matching interactions are
+// used, the last one keeps being replayed.
+func (t *Transport) find(incoming Request) (Interaction, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := -1
+	for idx, interaction := range t.cassette.Interactions {
+		if !matches(t.config.matchers, incoming, interaction.Request) {
+			continue
+		}
+
+		if !t.used[idx] {
+			t.used[idx] = true
+			return interaction, true
+		}
+
+		last = idx
+	}
+
+	if last < 0 {
+		return Interaction{}, false
+	}
+
+	return t.cassette.Interactions[last], true
+}
+
+// record sends req and appends the exchange to the cassette when its status matches MatcherConfig.
+func (t *Transport) record(req *http.Request, incoming Request) (*http.Response, error) {
+	res, err := t.tp.RoundTrip(req)
+
+	var response Response
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		if !t.matcher.Match(req, res.StatusCode) {
+			return res, nil
+		}
+
+		resBody, readErr := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		res.Body = io.NopCloser(bytes.NewReader(resBody))
+		response = Response{
+			StatusCode: res.StatusCode,
+			Headers:    t.redactor.RedactHeader(res.Header),
+		}
+		// resBody is handed back to the client, only a copy of it is redacted.
+		response.Body, response.BodyEncoding = encodeBody(t.redactor.RedactBody(res.Header.Get("Content-Type"), bytes.Clone(resBody)))
+	}
+
+	t.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{Request: incoming, Response: response})
+	t.used = append(t.used, true)
+	saveErr := t.cassette.Save(t.path)
+	t.mu.Unlock()
+
+	if saveErr != nil {
+		if res != nil {
+			_ = res.Body.Close()
+		}
+
+		return nil, fmt.Errorf("cassette: save %s: %w", t.path, saveErr)
+	}
+
+	return res, err
+}
+
+// request describes req the way it is recorded, redacted.
+func (t *Transport) request(req *http.Request, body []byte) Request {
+	u := t.redactor.RedactURL(req.URL)
+	u.Fragment, u.RawFragment = "", ""
+
+	r := Request{Method: req.Method, URL: u.String()}
+	if len(req.Header) > 0 {
+		r.Headers = t.redactor.RedactHeader(req.Header)
+	}
+
+	if len(body) > 0 {
+		// body is sent upstream, only a copy of it is redacted.
+		r.Body, r.BodyEncoding = encodeBody(t.redactor.RedactBody(req.Header.Get("Content-Type"), bytes.Clone(body)))
+	}
+
+	return r
+}
+
+// readRequestBody reads the body of req and returns a shallow copy of req with an unread copy of it.
+func readRequestBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req = req.WithContext(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return req, body, nil
+}
+
+func replay(req *http.Request, recorded Response) (*http.Response, error) {
+	if recorded.Error != "" {
+		return nil, errors.New(recorded.Error)
+	}
+
+	body, err := decodeBody(recorded.Body, recorded.BodyEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: decode body: %w", err)
+	}
+
+	h := header(recorded.Headers)
+	if h.Get("Content-Length") != "" {
+		h.Set("Content-Length", strconv.Itoa(len(body))) // Redaction may have changed the length
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
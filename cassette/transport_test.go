@@ -0,0 +1,207 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dangnmh/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `","echo":` + string(body) + `,"token":"server-secret"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func get(t *testing.T, client *http.Client, url string, body string) (int, string) {
+	var reqBody io.Reader
+	method := http.MethodGet
+	if body != "" {
+		method, reqBody = http.MethodPost, strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	return res.StatusCode, string(data)
+}
+
+func TestTransport_RecordReplay(t *testing.T) {
+	for _, name := range []string{"users.yaml", "users.json"} {
+		t.Run(name, func(t *testing.T) {
+			server, calls := newServer(t)
+			path := filepath.Join(t.TempDir(), name)
+
+			recorder, err := NewTransport(http.DefaultTransport, path, OptionMode(ModeRecord))
+			require.NoError(t, err)
+			client := &http.Client{Transport: recorder}
+
+			status, body := get(t, client, server.URL+"/users?b=2&a=1", `{"password":"hunter2"}`)
+			require.Equal(t, http.StatusOK, status)
+			require.Contains(t, body, `"token":"server-secret"`, "the caller gets the real response")
+
+			status, _ = get(t, client, server.URL+"/missing", "")
+			require.Equal(t, http.StatusNotFound, status)
+			require.Equal(t, 2, *calls)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NotContains(t, string(data), "hunter2")
+			require.NotContains(t, string(data), "server-secret")
+			require.NotContains(t, string(data), "Bearer secret")
+
+			player, err := NewTransport(http.DefaultTransport, path)
+			require.NoError(t, err)
+			client = &http.Client{Transport: player}
+
+			status, body = get(t, client, server.URL+"/users?a=1&b=2", `{"password":"other"}`)
+			require.Equal(t, http.StatusOK, status)
+			require.Contains(t, body, `"token":"[REDACTED]"`)
+
+			status, _ = get(t, client, server.URL+"/missing", "")
+			require.Equal(t, http.StatusNotFound, status)
+			require.Equal(t, 2, *calls, "replay does not reach the server")
+
+			_, err = client.Get(server.URL + "/unknown")
+			require.ErrorIs(t, err, ErrInteractionNotFound)
+		})
+	}
+}
+
+func TestTransport_RecordForm(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		_, _ = w.Write([]byte("access_token=abcdefghijklmnopqrstuvwxyz&ok=1"))
+	}))
+	t.Cleanup(server.Close)
+
+	path := filepath.Join(t.TempDir(), "form.json")
+	recorder, err := NewTransport(http.DefaultTransport, path, OptionMode(ModeRecord))
+	require.NoError(t, err)
+
+	res, err := (&http.Client{Transport: recorder}).Post(server.URL+"/token", "application/x-www-form-urlencoded",
+		strings.NewReader("password=correcthorsebattery&user=jo"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	require.Equal(t, "password=correcthorsebattery&user=jo", received, "the server gets the real request")
+	require.Equal(t, "access_token=abcdefghijklmnopqrstuvwxyz&ok=1", string(body), "the caller gets the real response")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "correcthorsebattery")
+	require.NotContains(t, string(data), "abcdefghijklmnopqrstuvwxyz")
+}
+
+func TestTransport_InvalidMatcherConfig(t *testing.T) {
+	var out strings.Builder
+	_, err := NewTransport(http.DefaultTransport, filepath.Join(t.TempDir(), "invalid.json"),
+		OptionMode(ModeRecord),
+		OptionLogger(slog.New(slog.NewTextHandler(&out, nil))),
+		OptionMatcherConfig(transport.MatcherConfig{BlackListPaths: []string{"GET|/admin/["}}),
+	)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "middleware="+layer)
+}
+
+func TestTransport_Matchers(t *testing.T) {
+	server, _ := newServer(t)
+	path := filepath.Join(t.TempDir(), "body.json")
+
+	recorder, err := NewTransport(http.DefaultTransport, path, OptionMode(ModeRecord))
+	require.NoError(t, err)
+	get(t, &http.Client{Transport: recorder}, server.URL+"/echo", `{"a":1,"b":2}`)
+
+	player, err := NewTransport(http.DefaultTransport, path,
+		OptionMatchers(MatchMethod, MatchURL, MatchBody, MatchHeaders("Content-Type")))
+	require.NoError(t, err)
+	client := &http.Client{Transport: player}
+
+	status, _ := get(t, client, server.URL+"/echo", `{"b":2, "a":1}`)
+	require.Equal(t, http.StatusOK, status)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/echo", strings.NewReader(`{"a":2}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	_, err = client.Do(req)
+	require.ErrorIs(t, err, ErrInteractionNotFound)
+}
+
+func TestTransport_Sequence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequence.json")
+	c := &Cassette{Version: Version, Interactions: []Interaction{
+		{Request: Request{Method: http.MethodGet, URL: "http://example.com/jobs"}, Response: Response{Error: "connection reset"}},
+		{Request: Request{Method: http.MethodGet, URL: "http://example.com/jobs"}, Response: Response{StatusCode: http.StatusAccepted}},
+		{Request: Request{Method: http.MethodGet, URL: "http://example.com/jobs"}, Response: Response{StatusCode: http.StatusOK, Body: "done"}},
+	}}
+	require.NoError(t, c.Save(path))
+
+	player, err := NewTransport(nil, path)
+	require.NoError(t, err)
+	client := &http.Client{Transport: player}
+
+	_, err = client.Get("http://example.com/jobs")
+	require.ErrorContains(t, err, "connection reset")
+
+	for _, status := range []int{http.StatusAccepted, http.StatusOK, http.StatusOK} {
+		res, err := client.Get("http://example.com/jobs")
+		require.NoError(t, err)
+		require.Equal(t, status, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	}
+}
+
+func TestTransport_ReplayOrRecord(t *testing.T) {
+	server, calls := newServer(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "mixed.yml")
+
+	_, err := NewTransport(http.DefaultTransport, path)
+	require.True(t, errors.Is(err, ErrCassetteNotFound))
+
+	for range 2 {
+		tp, err := NewTransport(http.DefaultTransport, path, OptionMode(ModeReplayOrRecord))
+		require.NoError(t, err)
+
+		status, _ := get(t, &http.Client{Transport: tp}, server.URL+"/users", "")
+		require.Equal(t, http.StatusOK, status)
+	}
+
+	require.Equal(t, 1, *calls)
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, c.Interactions, 1)
+}
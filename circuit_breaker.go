@@ -1,8 +1,10 @@
 package transport
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"log/slog"
 
@@ -10,16 +12,19 @@ import (
 )
 
 type circuitBreakerTransport struct {
-	tp      http.RoundTripper
-	breaker *gobreaker.CircuitBreaker[*http.Response]
-	logger  *slog.Logger
-	matcher Matcher
+	tp       http.RoundTripper
+	breaker  *gobreaker.CircuitBreaker[*http.Response]
+	logger   *slog.Logger
+	matcher  Matcher
+	observer Observer
 }
 
 type circuitBreakerConfig struct {
 	MatcherConfig
+	matcher       Matcher // Replaces MatcherConfig when set
 	logger        *slog.Logger
 	breakerConfig gobreaker.Settings
+	observer      Observer
 }
 
 var DefaultCircuitBreakerConfig = circuitBreakerConfig{
@@ -34,36 +39,90 @@ func NewCircuitBreakerTransport(tp http.RoundTripper, opts ...CircuitBreakerOpti
 		opt(&cfg)
 	}
 
+	settings := cfg.breakerConfig
+	isSuccessful := settings.IsSuccessful
+	settings.IsSuccessful = func(err error) bool {
+		var unmatched *unmatchedError
+		switch {
+		case errors.As(err, &unmatched):
+			return true
+		case isSuccessful != nil:
+			return isSuccessful(err)
+		default:
+			return err == nil
+		}
+	}
+
+	// Transitions are reported once, from the breaker itself: no single request owns them.
+	onStateChange := settings.OnStateChange
+	settings.OnStateChange = func(name string, from, to gobreaker.State) {
+		if onStateChange != nil {
+			onStateChange(name, from, to)
+		}
+
+		Notify(context.Background(), cfg.observer, &BreakerStateChangeEvent{From: from.String(), To: to.String()})
+	}
+
 	return &circuitBreakerTransport{
-		tp:      tp,
-		logger:  cfg.logger,
-		breaker: gobreaker.NewCircuitBreaker[*http.Response](cfg.breakerConfig),
-		matcher: NewMatcher(cfg.MatcherConfig),
+		tp:       tp,
+		logger:   cfg.logger,
+		breaker:  gobreaker.NewCircuitBreaker[*http.Response](settings),
+		matcher:  MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, cfg.logger, LayerCircuitBreaker),
+		observer: cfg.observer,
 	}
 }
 
+// unmatchedError carries an error the matcher does not select through the breaker, which counts it
+// as a success.
+type unmatchedError struct {
+	err error
+}
+
+func (e *unmatchedError) Error() string { return e.err.Error() }
+func (e *unmatchedError) Unwrap() error { return e.err }
+
 func (cbt *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if !cbt.matcher.MatchPath(req) {
 		return cbt.tp.RoundTrip(req)
 	}
 
+	end := observeRequest(cbt.observer, LayerCircuitBreaker, req)
 	result, err := cbt.breaker.Execute(func() (*http.Response, error) {
+		start := time.Now()
 		res, err := cbt.tp.RoundTrip(req)
+		matched := MatchOutcome(cbt.matcher, req, newOutcome(res, err, time.Since(start)))
+		if err != nil && !matched {
+			return nil, &unmatchedError{err: err}
+		}
+
 		if err != nil {
 			return nil, err
 		}
 
-		if cbt.matcher.Match(req, res.StatusCode) {
+		if matched {
+			discardBody(res)
 			return nil, errors.New("server error")
 		}
 
 		return res, nil
 	})
 
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		Notify(req.Context(), cbt.observer, &BreakerRejectedEvent{Request: req, State: cbt.breaker.State().String(), Err: err})
+	}
+
+	var unmatched *unmatchedError
+	if errors.As(err, &unmatched) {
+		err = unmatched.err
+	}
+
 	if err != nil {
 		cbt.logger.WarnContext(req.Context(), "Circuit breaker triggered", slog.String("error", err.Error()))
+		end(nil, err)
 		return nil, err
 	}
 
+	end(result, nil)
+
 	return result, nil
 }
@@ -15,6 +15,15 @@ func CircuitBreakerOptionMatcherConfig(config MatcherConfig) CircuitBreakerOptio
 	}
 }
 
+// CircuitBreakerOptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func CircuitBreakerOptionMatcher(matcher Matcher) CircuitBreakerOption {
+	return func(c *circuitBreakerConfig) *circuitBreakerConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
 func CircuitBreakerOptionLogger(logger *slog.Logger) CircuitBreakerOption {
 	return func(c *circuitBreakerConfig) *circuitBreakerConfig {
 		c.logger = logger
@@ -28,3 +37,11 @@ func CircuitBreakerOptionBreakerConfig(config gobreaker.Settings) CircuitBreaker
 		return c
 	}
 }
+
+// CircuitBreakerOptionObserver reports breaker state changes and rejections to observer.
+func CircuitBreakerOptionObserver(observer Observer) CircuitBreakerOption {
+	return func(c *circuitBreakerConfig) *circuitBreakerConfig {
+		c.observer = observer
+		return c
+	}
+}
@@ -0,0 +1,286 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httputil"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DumpFormat selects what the dump transport writes for each exchange. Formats combine with |.
+type DumpFormat int
+
+const (
+	DumpFormatCurl DumpFormat = 1 << iota // The request as a curl command reproducing it
+	DumpFormatWire                        // Request and response as raw HTTP/1.1 messages
+)
+
+type dumpTransport struct {
+	tp       http.RoundTripper
+	config   *dumpConfig
+	matcher  Matcher
+	redactor *redactor
+	mu       sync.Mutex // Serializes writes to config.writer
+}
+
+type dumpConfig struct {
+	MatcherConfig
+	matcher Matcher // Replaces MatcherConfig when set
+	RedactConfig
+	format      DumpFormat
+	maxBodySize int          // Bodies longer than this are cut, 0 means unlimited
+	writer      io.Writer    // Receives the dumps as text when set, instead of logger
+	logger      *slog.Logger // Receives one entry per exchange when writer is nil
+	level       slog.Level
+	observer    Observer
+}
+
+var DefaultDumpConfig = dumpConfig{
+	MatcherConfig: MatcherConfig{
+		OnStatus:       []int{NumberZero},
+		WhiteListPaths: []string{ConsCharStar},
+	},
+	RedactConfig: DefaultRedactConfig,
+	format:       DumpFormatCurl,
+	logger:       defaultLogger,
+	level:        slog.LevelDebug,
+}
+
+// NewTransportDump writes matching exchanges as redacted curl commands and/or HTTP/1.1 wire dumps,
+// for reproducing issues outside the application. Exchanges are written once the response body is
+// closed, or when the wrapped transport fails.
+func NewTransportDump(tp http.RoundTripper, opts ...DumpOption) http.RoundTripper {
+	cfg := DefaultDumpConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &dumpTransport{
+		tp:       tp,
+		config:   &cfg,
+		matcher:  MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, cfg.logger, LayerDump),
+		redactor: newRedactor(cfg.RedactConfig),
+	}
+}
+
+func (dt *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !dt.matcher.MatchPath(req) {
+		return dt.tp.RoundTrip(req)
+	}
+
+	end := observeRequest(dt.config.observer, LayerDump, req)
+	res, err := dt.roundTrip(req)
+	end(res, err)
+
+	return res, err
+}
+
+func (dt *dumpTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	if dt.config.writer == nil && !dt.config.logger.Enabled(req.Context(), dt.config.level) {
+		return dt.tp.RoundTrip(req)
+	}
+
+	req, _ = ensureRequestID(req)
+
+	var reqBody *bodyCapture
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = newBodyCapture(req.Body, captureLimit(dt.config.maxBodySize), nil)
+		req = req.WithContext(req.Context())
+		req.Body = reqBody
+	}
+
+	res, err := dt.tp.RoundTrip(req)
+	if err != nil {
+		dt.write(req.Context(), dt.dumpRequest(req, reqBody), "", err)
+		return nil, err
+	}
+
+	if !dt.matcher.Match(req, res.StatusCode) {
+		return res, nil
+	}
+
+	dumpEntry := func(resBody *bodyCapture) {
+		dt.write(req.Context(), dt.dumpRequest(req, reqBody), dt.dumpResponse(res, resBody), nil)
+	}
+
+	if res.Body == nil || res.Body == http.NoBody {
+		dumpEntry(nil)
+		return res, nil
+	}
+
+	limit := captureLimit(dt.config.maxBodySize)
+	if dt.config.format&DumpFormatWire == 0 {
+		limit = -1
+	}
+
+	res.Body = newBodyCapture(res.Body, limit, dumpEntry)
+
+	return res, nil
+}
+
+// dumpRequest is the text of the request in the configured formats.
+type dumpRequest struct {
+	curl string
+	wire string
+}
+
+func (dt *dumpTransport) dumpRequest(req *http.Request, reqBody *bodyCapture) dumpRequest {
+	header := http.Header(dt.redactor.sanitizeHeaders(req.Header))
+	body, note := dt.body(req.Header, reqBody)
+
+	var dump dumpRequest
+	if dt.config.format&DumpFormatCurl != 0 {
+		dump.curl = dt.curlCommand(req, header, body, note)
+	}
+
+	if dt.config.format&DumpFormatWire != 0 {
+		redacted := req.Clone(req.Context())
+		redacted.URL = dt.redactor.redactURL(req.URL)
+		redacted.Header = header
+		redacted.Body = io.NopCloser(strings.NewReader("")) // Only the length matters when dumping the head
+
+		head, err := httputil.DumpRequestOut(redacted, false)
+		if err != nil {
+			head = []byte(fmt.Sprintf("%s %s HTTP/1.1\r\n\r\n", req.Method, redacted.URL.RequestURI()))
+		}
+
+		dump.wire = string(head) + string(body) + note
+	}
+
+	return dump
+}
+
+func (dt *dumpTransport) dumpResponse(res *http.Response, resBody *bodyCapture) string {
+	if dt.config.format&DumpFormatWire == 0 {
+		return ""
+	}
+
+	redacted := *res
+	redacted.Header = http.Header(dt.redactor.sanitizeHeaders(res.Header))
+	redacted.Body = http.NoBody
+
+	head, err := httputil.DumpResponse(&redacted, false)
+	if err != nil {
+		head = []byte(fmt.Sprintf("%s %s\r\n\r\n", res.Proto, res.Status))
+	}
+
+	body, note := dt.body(res.Header, resBody)
+
+	return string(head) + string(body) + note
+}
+
+// body returns the redacted, decompressed text of a captured body and a note describing what was
+// left out of it.
+func (dt *dumpTransport) body(header http.Header, capture *bodyCapture) ([]byte, string) {
+	if capture == nil {
+		return nil, ""
+	}
+
+	body := prepareBody(capture, header, dt.redactor, dt.config.maxBodySize)
+	switch {
+	case body.binary:
+		return nil, fmt.Sprintf("[binary body of %d bytes]", capture.bytesRead())
+	case body.truncated:
+		return body.data, fmt.Sprintf("[truncated, %d bytes in total]", capture.bytesRead())
+	default:
+		return body.data, ""
+	}
+}
+
+// curlCommand renders a request as a shell-quoted curl command. header is already redacted.
+func (dt *dumpTransport) curlCommand(req *http.Request, header http.Header, body []byte, note string) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != http.MethodGet || len(body) > 0 {
+		b.WriteString(" -X " + req.Method)
+	}
+
+	u := dt.redactor.redactURL(req.URL)
+	u.Fragment, u.RawFragment = "", ""
+	b.WriteString(" " + shellQuote(u.String()))
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		b.WriteString(" -H " + shellQuote("Host: "+req.Host))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(header)) {
+		for _, value := range header[key] {
+			b.WriteString(" -H " + shellQuote(key+": "+value))
+		}
+	}
+
+	if strings.Contains(strings.ToLower(header.Get("Accept-Encoding")), "gzip") {
+		b.WriteString(" --compressed")
+	}
+
+	if len(body) > 0 {
+		b.WriteString(" --data-raw " + shellQuote(string(body)))
+	}
+
+	if note != "" {
+		b.WriteString(" # body " + note)
+	}
+
+	return b.String()
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// write sends one exchange to the writer, or to the logger when no writer is configured.
+func (dt *dumpTransport) write(ctx context.Context, req dumpRequest, res string, err error) {
+	requestID := RequestIDFromContext(ctx)
+
+	if dt.config.writer == nil {
+		attrs := []slog.Attr{slog.String("request_id", requestID)}
+		if req.curl != "" {
+			attrs = append(attrs, slog.String("curl", req.curl))
+		}
+
+		if req.wire != "" {
+			attrs = append(attrs, slog.String("request", req.wire))
+		}
+
+		if res != "" {
+			attrs = append(attrs, slog.String("response", res))
+		}
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		dt.config.logger.LogAttrs(ctx, dt.config.level, "HTTP dump", attrs...)
+		return
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# request_id: %s\n", requestID)
+	if req.curl != "" {
+		b.WriteString(req.curl + "\n\n")
+	}
+
+	if req.wire != "" {
+		b.WriteString(strings.TrimRight(req.wire, "\r\n") + "\n\n")
+	}
+
+	if res != "" {
+		b.WriteString(strings.TrimRight(res, "\r\n") + "\n\n")
+	}
+
+	if err != nil {
+		fmt.Fprintf(&b, "# error: %s\n\n", err)
+	}
+
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	_, _ = dt.config.writer.Write(b.Bytes())
+}
@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"io"
+	"log/slog"
+)
+
+type DumpOption func(*dumpConfig) *dumpConfig
+
+func DumpOptionMatcherConfig(config MatcherConfig) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.MatcherConfig = config
+		return c
+	}
+}
+
+// DumpOptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func DumpOptionMatcher(matcher Matcher) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// DumpOptionRedactConfig replaces the redaction rules, shared with NewTransportLog, applied to dumps.
+func DumpOptionRedactConfig(config RedactConfig) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.RedactConfig = config
+		return c
+	}
+}
+
+// DumpOptionFormat selects the dump formats, e.g. DumpFormatCurl|DumpFormatWire.
+func DumpOptionFormat(format DumpFormat) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.format = format
+		return c
+	}
+}
+
+// DumpOptionMaxBodySize cuts dumped bodies longer than size bytes, 0 dumps them whole.
+func DumpOptionMaxBodySize(size int) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.maxBodySize = size
+		return c
+	}
+}
+
+// DumpOptionWriter writes dumps as plain text to w instead of the logger.
+func DumpOptionWriter(w io.Writer) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.writer = w
+		return c
+	}
+}
+
+func DumpOptionLogger(logger *slog.Logger) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.logger = logger
+		return c
+	}
+}
+
+// DumpOptionLevel sets the level of the logged dumps, Debug by default.
+func DumpOptionLevel(level slog.Level) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.level = level
+		return c
+	}
+}
+
+// DumpOptionObserver reports request start and end events of the dump transport to observer.
+func DumpOptionObserver(observer Observer) DumpOption {
+	return func(c *dumpConfig) *dumpConfig {
+		c.observer = observer
+		return c
+	}
+}
@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readingRoundTripper consumes the request body like a real transport before answering.
+type readingRoundTripper struct {
+	staticRoundTripper
+	body []byte
+}
+
+func (r *readingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		r.body, _ = io.ReadAll(req.Body)
+	}
+
+	res, err := r.staticRoundTripper.RoundTrip(req)
+	if res != nil {
+		res.Header.Set("Content-Type", "application/json")
+		res.Header.Set("Set-Cookie", "session=abc")
+	}
+
+	return res, err
+}
+
+func TestDumpTransport_Curl(t *testing.T) {
+	var out bytes.Buffer
+	rt := &readingRoundTripper{staticRoundTripper: staticRoundTripper{status: http.StatusOK, body: `{"ok":true}`}}
+	client := &http.Client{
+		Transport: NewTransportDump(rt,
+			DumpOptionMatcherConfig(logAllMatcherConfig),
+			DumpOptionWriter(&out),
+		),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.example.com/users?token=secret&page=1",
+		strings.NewReader(`{"name":"it's me","password":"hunter2"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set(HeaderRequestID, "req-1")
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	_, _ = io.ReadAll(res.Body)
+	require.NoError(t, res.Body.Close())
+
+	require.Equal(t, `{"name":"it's me","password":"hunter2"}`, string(rt.body))
+	require.Equal(t, "# request_id: req-1\n"+
+		`curl -X POST 'https://api.example.com/users?page=1&token=%5BREDACTED%5D'`+
+		` -H 'Authorization: [REDACTED]' -H 'Content-Type: application/json' -H 'X-Request-Id: req-1'`+
+		` --data-raw '{"name":"it'\''s me","password":"[REDACTED]"}'`+"\n\n", out.String())
+}
+
+func TestDumpTransport_Wire(t *testing.T) {
+	var out bytes.Buffer
+	client := &http.Client{
+		Transport: NewTransportDump(
+			&readingRoundTripper{staticRoundTripper: staticRoundTripper{status: http.StatusOK, body: `{"token":"abc"}`}},
+			DumpOptionMatcherConfig(logAllMatcherConfig),
+			DumpOptionFormat(DumpFormatWire),
+			DumpOptionWriter(&out),
+		),
+	}
+
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	_, _ = io.ReadAll(res.Body)
+	require.NoError(t, res.Body.Close())
+
+	dump := out.String()
+	require.Contains(t, dump, "GET /v1/api?keyword=hhh&search=abc HTTP/1.1\r\nHost: example.com\r\n")
+	require.Contains(t, dump, "200 OK\r\n")
+	require.Contains(t, dump, "Set-Cookie: [REDACTED]\r\n")
+	require.Contains(t, dump, `{"token":"[REDACTED]"}`)
+	require.NotContains(t, dump, "curl")
+}
+
+func TestDumpTransport_Logger(t *testing.T) {
+	handler := &testLogHandler{}
+	client := &http.Client{
+		Transport: NewTransportDump(&staticRoundTripper{err: errors.New("connection refused")},
+			DumpOptionMatcherConfig(logAllMatcherConfig),
+			DumpOptionFormat(DumpFormatCurl|DumpFormatWire),
+			DumpOptionLogger(slog.New(handler)),
+			DumpOptionMaxBodySize(4),
+		),
+	}
+
+	_, err := client.Post(defaultURL, "text/plain", strings.NewReader("hello world"))
+	require.Error(t, err)
+
+	require.Equal(t, []slog.Level{slog.LevelDebug}, handler.levels())
+	attrs := handler.attrs(0)
+	require.Equal(t, "connection refused", attrs["error"].String())
+	require.NotEmpty(t, attrs["request_id"].String())
+	require.Contains(t, attrs["request"].String(), "POST /v1/api?keyword=hhh&search=abc HTTP/1.1\r\nHost: example.com\r\n")
+
+	// The failing transport never read the body, so the curl command carries none.
+	require.Equal(t, "curl -X POST 'http://example.com/v1/api?keyword=hhh&search=abc' -H 'Content-Type: text/plain'",
+		attrs["curl"].String())
+}
+
+func TestDumpTransport_DefaultConfig(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusNotFound, http.StatusServiceUnavailable} {
+		var out bytes.Buffer
+		client := &http.Client{
+			Transport: NewTransportDump(&staticRoundTripper{status: status}, DumpOptionWriter(&out)),
+		}
+
+		res, err := client.Get(defaultURL)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Contains(t, out.String(), "curl 'http://example.com/v1/api?", "status %d", status)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	require.Equal(t, `'plain'`, shellQuote("plain"))
+	require.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// ErrorClass is a coarse classification of transport errors, suitable for logs and metric labels.
+type ErrorClass string
+
+const (
+	ErrorClassNone     ErrorClass = ""
+	ErrorClassCanceled ErrorClass = "canceled"
+	ErrorClassTimeout  ErrorClass = "timeout"
+	ErrorClassDNS      ErrorClass = "dns"
+	ErrorClassTLS      ErrorClass = "tls"
+	ErrorClassRefused  ErrorClass = "refused"
+	ErrorClassReset    ErrorClass = "reset"
+	ErrorClassUnknown  ErrorClass = "unknown"
+)
+
+// ClassifyError returns the class of a transport error, ErrorClassNone for a nil error.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+
+	var (
+		dnsErr         *net.DNSError
+		recordErr      tls.RecordHeaderError
+		alertErr       tls.AlertError
+		verifyErr      *tls.CertificateVerificationError
+		unknownAuthErr x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		invalidCertErr x509.CertificateInvalidError
+		netErr         net.Error
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.As(err, &dnsErr):
+		return ErrorClassDNS
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr), errors.As(err, &invalidCertErr):
+		return ErrorClassTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassReset
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	default:
+		return ErrorClassUnknown
+	}
+}
+
+// contextState describes why a request context ended: canceled by the caller, deadline exceeded, or not at all.
+func contextState(ctx context.Context) string {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return "canceled"
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "deadline_exceeded"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,51 @@
+package transport
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testClassifyErrorCase struct {
+	name     string
+	err      error
+	expected ErrorClass
+}
+
+func TestClassifyError_Cases(t *testing.T) {
+	testCases := []*testClassifyErrorCase{
+		{name: "nil", err: nil, expected: ErrorClassNone},
+		{name: "canceled", err: fmt.Errorf("wrap: %w", context.Canceled), expected: ErrorClassCanceled},
+		{name: "deadline", err: &url.Error{Op: "Get", Err: context.DeadlineExceeded}, expected: ErrorClassTimeout},
+		{name: "netTimeout", err: &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, expected: ErrorClassTimeout},
+		{name: "dns", err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "x"}}, expected: ErrorClassDNS},
+		{name: "tls", err: fmt.Errorf("tls: %w", x509.UnknownAuthorityError{}), expected: ErrorClassTLS},
+		{name: "refused", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: ErrorClassRefused},
+		{name: "reset", err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, expected: ErrorClassReset},
+		{name: "eof", err: io.ErrUnexpectedEOF, expected: ErrorClassReset},
+		{name: "unknown", err: errTemporaryNetwork, expected: ErrorClassUnknown},
+	}
+
+	for _, test := range testCases {
+		require.Equal(t, test.expected, ClassifyError(test.err), test.name)
+	}
+}
+
+func TestClassifyError_Dial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	_, err = net.Dial("tcp", addr)
+	require.Error(t, err)
+	require.Equal(t, ErrorClassRefused, ClassifyError(err))
+}
@@ -0,0 +1,324 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// HeaderFaultInjected is set on synthetic responses of the fault transport.
+const HeaderFaultInjected = "X-Fault-Injected"
+
+// ErrFaultInjected is wrapped by every error the fault transport injects.
+var ErrFaultInjected = errors.New("transport: injected fault")
+
+// FaultKind names a failure mode of the fault transport.
+type FaultKind string
+
+const (
+	FaultLatency      FaultKind = "latency"       // Delay the request before sending it
+	FaultStatus       FaultKind = "status"        // Answer with a synthetic response without sending the request
+	FaultError        FaultKind = "error"         // Fail with a connection error without sending the request
+	FaultTimeout      FaultKind = "timeout"       // Hang, then fail with a timeout error without sending the request
+	FaultTruncateBody FaultKind = "truncate_body" // Cut the real response body short with io.ErrUnexpectedEOF
+	FaultSlowBody     FaultKind = "slow_body"     // Drip the real response body in small chunks
+)
+
+// Fault is one failure mode, injected into a matching request with Probability between 0 and 1.
+// Only the fields of its Kind are used.
+type Fault struct {
+	Kind        FaultKind
+	Probability float64
+
+	Latency       LatencyFunc   // FaultLatency
+	StatusCode    int           // FaultStatus
+	Body          string        // FaultStatus
+	Err           error         // FaultError, a connection refused error when nil
+	Timeout       time.Duration // FaultTimeout, how long the request hangs before failing
+	TruncateAfter int           // FaultTruncateBody, bytes delivered before the cut
+	ChunkSize     int           // FaultSlowBody, bytes per read
+	ChunkInterval time.Duration // FaultSlowBody, pause before each read
+}
+
+// LatencyFunc draws an added latency from r.
+type LatencyFunc func(r *rand.Rand) time.Duration
+
+// FixedLatency always adds d.
+func FixedLatency(d time.Duration) LatencyFunc {
+	return func(*rand.Rand) time.Duration {
+		return d
+	}
+}
+
+// UniformLatency adds a latency drawn uniformly between min and max.
+func UniformLatency(min, max time.Duration) LatencyFunc {
+	return func(r *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+
+		return min + time.Duration(r.Int64N(int64(max-min)))
+	}
+}
+
+// NormalLatency adds a latency drawn from a normal distribution, never below zero.
+func NormalLatency(mean, stddev time.Duration) LatencyFunc {
+	return func(r *rand.Rand) time.Duration {
+		return max(time.Duration(r.NormFloat64()*float64(stddev))+mean, 0)
+	}
+}
+
+// FaultTransport injects faults into the requests it matches. It can be switched on and off while in use.
+type FaultTransport struct {
+	tp      http.RoundTripper
+	config  *faultConfig
+	matcher Matcher
+	enabled atomic.Bool
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+type faultConfig struct {
+	MatcherConfig
+	matcher  Matcher // Replaces MatcherConfig when set
+	faults   []Fault // Rolled in order, the first status, error or timeout fault drawn ends the request
+	seed     uint64
+	seeded   bool
+	disabled bool
+	observer Observer
+}
+
+var DefaultFaultConfig = faultConfig{
+	MatcherConfig: DefaultMatcherConfig,
+}
+
+// NewTransportFault injects the configured faults into matching requests, for exercising retry and
+// circuit breaker settings against failures the upstream does not produce on demand.
+func NewTransportFault(tp http.RoundTripper, opts ...FaultOption) *FaultTransport {
+	cfg := DefaultFaultConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	seed := cfg.seed
+	if !cfg.seeded {
+		seed = rand.Uint64()
+	}
+
+	ft := &FaultTransport{
+		tp:      tp,
+		config:  &cfg,
+		matcher: MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, defaultLogger, LayerFault),
+		rng:     rand.New(rand.NewPCG(seed, seed)),
+	}
+	ft.enabled.Store(!cfg.disabled)
+
+	return ft
+}
+
+// Enable starts injecting faults.
+func (ft *FaultTransport) Enable() {
+	ft.enabled.Store(true)
+}
+
+// Disable stops injecting faults, requests pass through unchanged.
+func (ft *FaultTransport) Disable() {
+	ft.enabled.Store(false)
+}
+
+func (ft *FaultTransport) Enabled() bool {
+	return ft.enabled.Load()
+}
+
+func (ft *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !ft.Enabled() || !ft.matcher.MatchPath(req) {
+		return ft.tp.RoundTrip(req)
+	}
+
+	drawn, delay := ft.draw()
+	if delay > 0 {
+		ft.notify(req, &FaultInjectedEvent{Request: req, Kind: FaultLatency, Delay: delay})
+		if err := sleepContext(req.Context(), delay); err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+	}
+
+	var bodyFaults []Fault
+	for _, fault := range drawn {
+		switch fault.Kind {
+		case FaultStatus:
+			ft.notify(req, &FaultInjectedEvent{Request: req, Kind: fault.Kind, StatusCode: fault.StatusCode})
+			closeRequestBody(req)
+			return syntheticResponse(req, fault), nil
+		case FaultError:
+			err := fault.Err
+			if err == nil {
+				err = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+			}
+
+			err = fmt.Errorf("%w: %w", ErrFaultInjected, err)
+			ft.notify(req, &FaultInjectedEvent{Request: req, Kind: fault.Kind, Err: err})
+			closeRequestBody(req)
+			return nil, err
+		case FaultTimeout:
+			ft.notify(req, &FaultInjectedEvent{Request: req, Kind: fault.Kind, Delay: fault.Timeout})
+			closeRequestBody(req)
+			if err := sleepContext(req.Context(), fault.Timeout); err != nil {
+				return nil, err
+			}
+
+			return nil, faultTimeoutError{}
+		case FaultTruncateBody, FaultSlowBody:
+			bodyFaults = append(bodyFaults, fault)
+		}
+	}
+
+	res, err := ft.tp.RoundTrip(req)
+	if err != nil || res.Body == nil {
+		return res, err
+	}
+
+	for _, fault := range bodyFaults {
+		ft.notify(req, &FaultInjectedEvent{Request: req, Kind: fault.Kind})
+		if fault.Kind == FaultTruncateBody {
+			res.Body = &truncatedBody{rc: res.Body, remaining: fault.TruncateAfter}
+		} else {
+			res.Body = &slowBody{rc: res.Body, ctx: req.Context(), chunk: max(fault.ChunkSize, 1), interval: fault.ChunkInterval}
+		}
+	}
+
+	return res, nil
+}
+
+// draw rolls every fault once, in order, and returns the drawn ones with the total latency to add.
+func (ft *FaultTransport) draw() ([]Fault, time.Duration) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	var (
+		drawn []Fault
+		delay time.Duration
+	)
+
+	for _, fault := range ft.config.faults {
+		if ft.rng.Float64() >= fault.Probability {
+			continue
+		}
+
+		if fault.Kind == FaultLatency {
+			if fault.Latency != nil {
+				delay += fault.Latency(ft.rng)
+			}
+
+			continue
+		}
+
+		drawn = append(drawn, fault)
+	}
+
+	return drawn, delay
+}
+
+func (ft *FaultTransport) notify(req *http.Request, event *FaultInjectedEvent) {
+	Notify(req.Context(), ft.config.observer, event)
+}
+
+func syntheticResponse(req *http.Request, fault Fault) *http.Response {
+	header := http.Header{}
+	header.Set(HeaderFaultInjected, string(FaultStatus))
+	header.Set("Content-Length", strconv.Itoa(len(fault.Body)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fault.StatusCode, http.StatusText(fault.StatusCode)),
+		StatusCode:    fault.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(fault.Body))),
+		ContentLength: int64(len(fault.Body)),
+		Request:       req,
+	}
+}
+
+// closeRequestBody closes the body of a request that is answered without being sent, as the
+// RoundTripper contract requires.
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// faultTimeoutError is a net.Error reporting a timeout, like the ones of a hanging connection.
+type faultTimeoutError struct{}
+
+func (faultTimeoutError) Error() string   { return ErrFaultInjected.Error() + ": i/o timeout" }
+func (faultTimeoutError) Timeout() bool   { return true }
+func (faultTimeoutError) Temporary() bool { return true }
+func (faultTimeoutError) Unwrap() error   { return ErrFaultInjected }
+
+// truncatedBody ends a body with io.ErrUnexpectedEOF after remaining bytes.
+type truncatedBody struct {
+	rc        io.ReadCloser
+	remaining int
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, fmt.Errorf("%w: %w", ErrFaultInjected, io.ErrUnexpectedEOF)
+	}
+
+	n, err := b.rc.Read(p[:min(len(p), b.remaining)])
+	b.remaining -= n
+
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.rc.Close()
+}
+
+// slowBody delivers at most chunk bytes per read, waiting interval before each.
+type slowBody struct {
+	rc       io.ReadCloser
+	ctx      context.Context
+	chunk    int
+	interval time.Duration
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if err := sleepContext(b.ctx, b.interval); err != nil {
+		return 0, err
+	}
+
+	return b.rc.Read(p[:min(len(p), b.chunk)])
+}
+
+func (b *slowBody) Close() error {
+	return b.rc.Close()
+}
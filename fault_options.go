@@ -0,0 +1,83 @@
+package transport
+
+import "time"
+
+type FaultOption func(*faultConfig) *faultConfig
+
+func FaultOptionMatcherConfig(config MatcherConfig) FaultOption {
+	return func(c *faultConfig) *faultConfig {
+		c.MatcherConfig = config
+		return c
+	}
+}
+
+// FaultOptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func FaultOptionMatcher(matcher Matcher) FaultOption {
+	return func(c *faultConfig) *faultConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// FaultOptionFault adds a fault described in full. The helpers below cover each kind.
+func FaultOptionFault(fault Fault) FaultOption {
+	return func(c *faultConfig) *faultConfig {
+		c.faults = append(c.faults, fault)
+		return c
+	}
+}
+
+// FaultOptionLatency delays a request with probability, e.g. FixedLatency(time.Second).
+func FaultOptionLatency(probability float64, latency LatencyFunc) FaultOption {
+	return FaultOptionFault(Fault{Kind: FaultLatency, Probability: probability, Latency: latency})
+}
+
+// FaultOptionStatus answers a request with a synthetic status and body with probability.
+func FaultOptionStatus(probability float64, statusCode int, body string) FaultOption {
+	return FaultOptionFault(Fault{Kind: FaultStatus, Probability: probability, StatusCode: statusCode, Body: body})
+}
+
+// FaultOptionError fails a request with err with probability, a connection refused error when err is nil.
+func FaultOptionError(probability float64, err error) FaultOption {
+	return FaultOptionFault(Fault{Kind: FaultError, Probability: probability, Err: err})
+}
+
+// FaultOptionTimeout hangs a request for after, then fails it with a timeout error, with probability.
+func FaultOptionTimeout(probability float64, after time.Duration) FaultOption {
+	return FaultOptionFault(Fault{Kind: FaultTimeout, Probability: probability, Timeout: after})
+}
+
+// FaultOptionTruncateBody cuts a response body after the given number of bytes with probability.
+func FaultOptionTruncateBody(probability float64, after int) FaultOption {
+	return FaultOptionFault(Fault{Kind: FaultTruncateBody, Probability: probability, TruncateAfter: after})
+}
+
+// FaultOptionSlowBody drips a response body chunkSize bytes every interval with probability.
+func FaultOptionSlowBody(probability float64, chunkSize int, interval time.Duration) FaultOption {
+	return FaultOptionFault(Fault{Kind: FaultSlowBody, Probability: probability, ChunkSize: chunkSize, ChunkInterval: interval})
+}
+
+// FaultOptionSeed makes the drawn faults reproducible for the same sequence of requests.
+func FaultOptionSeed(seed uint64) FaultOption {
+	return func(c *faultConfig) *faultConfig {
+		c.seed, c.seeded = seed, true
+		return c
+	}
+}
+
+// FaultOptionEnabled sets whether faults are injected from the start, true by default.
+func FaultOptionEnabled(enable bool) FaultOption {
+	return func(c *faultConfig) *faultConfig {
+		c.disabled = !enable
+		return c
+	}
+}
+
+// FaultOptionObserver reports every injected fault as a FaultInjectedEvent to observer.
+func FaultOptionObserver(observer Observer) FaultOption {
+	return func(c *faultConfig) *faultConfig {
+		c.observer = observer
+		return c
+	}
+}
@@ -0,0 +1,165 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultTransport_Kinds(t *testing.T) {
+	testCases := []struct {
+		name      string
+		option    FaultOption
+		status    int
+		errClass  ErrorClass
+		body      string
+		bodyError bool
+	}{
+		{name: "status", option: FaultOptionStatus(1, http.StatusServiceUnavailable, "down"), status: http.StatusServiceUnavailable, body: "down"},
+		{name: "error", option: FaultOptionError(1, nil), errClass: ErrorClassRefused},
+		{name: "timeout", option: FaultOptionTimeout(1, time.Millisecond), errClass: ErrorClassTimeout},
+		{name: "truncate body", option: FaultOptionTruncateBody(1, 3), status: http.StatusOK, body: "hel", bodyError: true},
+		{name: "slow body", option: FaultOptionSlowBody(1, 2, time.Millisecond), status: http.StatusOK, body: "hello"},
+		{name: "latency", option: FaultOptionLatency(1, FixedLatency(time.Millisecond)), status: http.StatusOK, body: "hello"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			observer := &testObserver{}
+			client := &http.Client{
+				Transport: NewTransportFault(&staticRoundTripper{status: http.StatusOK, body: "hello"},
+					tc.option, FaultOptionObserver(observer)),
+			}
+
+			res, err := client.Get(defaultURL)
+			require.Equal(t, []string{EventFaultInjected}, observer.names())
+
+			if tc.errClass != ErrorClassNone {
+				require.ErrorIs(t, err, ErrFaultInjected)
+				require.Equal(t, tc.errClass, ClassifyError(err))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.status, res.StatusCode)
+
+			body, err := io.ReadAll(res.Body)
+			require.Equal(t, tc.body, string(body))
+			if tc.bodyError {
+				require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, res.Body.Close())
+		})
+	}
+}
+
+// closeTrackingBody records whether the request body was closed.
+type closeTrackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeTrackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestFaultTransport_ClosesRequestBody(t *testing.T) {
+	for name, option := range map[string]FaultOption{
+		"status":  FaultOptionStatus(1, http.StatusServiceUnavailable, ""),
+		"error":   FaultOptionError(1, nil),
+		"timeout": FaultOptionTimeout(1, 0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			body := &closeTrackingBody{Reader: strings.NewReader("payload")}
+			req, err := http.NewRequest(http.MethodPost, defaultURL, body)
+			require.NoError(t, err)
+
+			res, err := NewTransportFault(&staticRoundTripper{status: http.StatusOK}, option).RoundTrip(req)
+			if err == nil {
+				require.NoError(t, res.Body.Close())
+			}
+
+			require.True(t, body.closed, "requests answered by a fault are never sent")
+		})
+	}
+}
+
+func TestFaultTransport_Seed(t *testing.T) {
+	statuses := func() []int {
+		ft := NewTransportFault(&staticRoundTripper{status: http.StatusOK},
+			FaultOptionStatus(0.5, http.StatusBadGateway, ""),
+			FaultOptionSeed(42),
+		)
+
+		var statuses []int
+		for range 20 {
+			req, _ := http.NewRequest(http.MethodGet, defaultURL, nil)
+			res, err := ft.RoundTrip(req)
+			require.NoError(t, err)
+			statuses = append(statuses, res.StatusCode)
+		}
+
+		return statuses
+	}
+
+	first := statuses()
+	require.Equal(t, first, statuses())
+	require.Contains(t, first, http.StatusOK)
+	require.Contains(t, first, http.StatusBadGateway)
+}
+
+func TestFaultTransport_Switch(t *testing.T) {
+	ft := NewTransportFault(&staticRoundTripper{status: http.StatusOK},
+		FaultOptionError(1, errors.New("boom")),
+		FaultOptionEnabled(false),
+	)
+	client := &http.Client{Transport: ft}
+
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	ft.Enable()
+	_, err = client.Get(defaultURL)
+	require.ErrorContains(t, err, "boom")
+
+	ft.Disable()
+	require.False(t, ft.Enabled())
+	res, err = client.Get(defaultURL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+}
+
+func TestFaultTransport_Retry(t *testing.T) {
+	inner := &sequenceRoundTripper{statuses: []int{http.StatusOK}}
+	client := &http.Client{
+		Transport: NewTransportRetry(
+			NewTransportFault(inner, FaultOptionStatus(0.5, http.StatusServiceUnavailable, ""), FaultOptionSeed(7)),
+			RetryOptionMaxTries(10),
+		),
+	}
+
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, 1, inner.calls, "synthetic responses never reach the wrapped transport")
+}
+
+func TestLatencyFuncs(t *testing.T) {
+	ft := NewTransportFault(nil, FaultOptionSeed(1))
+	for range 100 {
+		d := UniformLatency(time.Millisecond, 2*time.Millisecond)(ft.rng)
+		require.GreaterOrEqual(t, d, time.Millisecond)
+		require.Less(t, d, 2*time.Millisecond)
+		require.GreaterOrEqual(t, NormalLatency(time.Millisecond, 10*time.Millisecond)(ft.rng), time.Duration(0))
+	}
+}
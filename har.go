@@ -0,0 +1,536 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrHARNoDestination is returned by HARRecorder.Flush when neither a writer nor a file is configured.
+var ErrHARNoDestination = errors.New("har: no flush destination configured")
+
+const (
+	harVersion     = "1.2"
+	harCreatorName = "github.com/dangnmh/transport"
+)
+
+// HAR is an HTTP Archive 1.2 document, see http://www.softwareishard.com/blog/har-12-spec/.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // Total milliseconds, the sum of the non-negative timings
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	Error           string      `json:"_error,omitempty"` // Transport error of a request that got no response
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string         `json:"mimeType"`
+	Params   []HARNameValue `json:"params"`
+	Text     string         `json:"text"`
+	Comment  string         `json:"comment,omitempty"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // base64 for binary bodies
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings are in milliseconds, -1 when the phase does not apply to the request.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // Includes SSL
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARRecorder keeps the exchanges sent through NewTransportHAR until they are flushed.
+type HARRecorder struct {
+	config   *harConfig
+	matcher  Matcher
+	redactor *redactor
+
+	mu      sync.Mutex
+	entries []HAREntry
+	flushes int
+
+	writeMu sync.Mutex // Serializes writes to config.writer
+}
+
+type harConfig struct {
+	MatcherConfig
+	matcher Matcher // Replaces MatcherConfig when set
+	RedactConfig
+	maxBodySize int       // Bodies longer than this are cut, 0 means unlimited
+	maxEntries  int       // Flush, or drop the oldest entry without a destination, once reached. 0 means unlimited
+	writer      io.Writer // Receives one HAR document per flush
+	file        string    // Path of the HAR files, numbered per flush
+	logger      *slog.Logger
+}
+
+var DefaultHARConfig = harConfig{
+	MatcherConfig: MatcherConfig{
+		OnStatus:       []int{NumberZero},
+		WhiteListPaths: []string{ConsCharStar},
+	},
+	RedactConfig: DefaultRedactConfig,
+	logger:       defaultLogger,
+}
+
+func NewHARRecorder(opts ...HAROption) *HARRecorder {
+	cfg := DefaultHARConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &HARRecorder{
+		config:   &cfg,
+		matcher:  MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, cfg.logger, LayerHAR),
+		redactor: newRedactor(cfg.RedactConfig),
+	}
+}
+
+type harTransport struct {
+	tp       http.RoundTripper
+	recorder *HARRecorder
+}
+
+// NewTransportHAR records every exchange matching the recorder's config into recorder. An exchange
+// is recorded once its response body is closed, or when the wrapped transport fails.
+func NewTransportHAR(tp http.RoundTripper, recorder *HARRecorder) http.RoundTripper {
+	return &harTransport{tp: tp, recorder: recorder}
+}
+
+func (ht *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := ht.recorder
+	if !r.matcher.MatchPath(req) {
+		return ht.tp.RoundTrip(req)
+	}
+
+	start := time.Now()
+	var reqBody *bodyCapture
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = newBodyCapture(req.Body, captureLimit(r.config.maxBodySize), nil)
+		req = req.WithContext(req.Context())
+		req.Body = reqBody
+	}
+
+	req, timing := WithRequestTiming(req)
+
+	res, err := ht.tp.RoundTrip(req)
+	if err != nil {
+		entry := r.newEntry(req, reqBody, timing, start)
+		entry.Error = err.Error()
+		r.add(entry)
+		return nil, err
+	}
+
+	if !r.matcher.Match(req, res.StatusCode) {
+		return res, nil
+	}
+
+	record := func(resBody *bodyCapture) {
+		entry := r.newEntry(req, reqBody, timing, start)
+		entry.Response = r.response(res, resBody)
+		entry.Timings.Receive = harMillis(time.Since(start) - timing.TimeToFirstByte())
+		entry.Time = entry.Timings.total()
+		r.add(entry)
+	}
+
+	if res.Body == nil || res.Body == http.NoBody {
+		record(nil)
+		return res, nil
+	}
+
+	res.Body = newBodyCapture(res.Body, captureLimit(r.config.maxBodySize), record)
+
+	return res, nil
+}
+
+// newEntry describes the request of an exchange, with a placeholder response.
+func (r *HARRecorder) newEntry(req *http.Request, reqBody *bodyCapture, timing *RequestTiming, start time.Time) HAREntry {
+	query := r.redactor.redactQuery(req.URL.Query())
+	queryString := make([]HARNameValue, 0, len(query))
+	for _, name := range slices.Sorted(maps.Keys(query)) {
+		for _, value := range query[name] {
+			queryString = append(queryString, HARNameValue{Name: name, Value: value})
+		}
+	}
+
+	u := r.redactor.redactURL(req.URL)
+	u.Fragment, u.RawFragment = "", ""
+
+	entry := HAREntry{
+		StartedDateTime: start,
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         u.String(),
+			HTTPVersion: harHTTPVersion(req.Proto),
+			Cookies:     r.cookies(req.Cookies(), "Cookie"),
+			Headers:     r.headers(req.Header),
+			QueryString: queryString,
+			HeadersSize: -1,
+		},
+		Response: HARResponse{
+			Cookies:     []HARCookie{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: timing.harTimings(),
+	}
+
+	if reqBody != nil {
+		entry.Request.BodySize = reqBody.bytesRead()
+		entry.Request.PostData = r.postData(req.Header, reqBody)
+	}
+
+	if host, _, err := net.SplitHostPort(timing.RemoteAddr()); err == nil {
+		entry.ServerIPAddress = host
+		entry.Connection = timing.LocalAddr()
+	}
+
+	entry.Time = entry.Timings.total()
+
+	return entry
+}
+
+func (r *HARRecorder) response(res *http.Response, resBody *bodyCapture) HARResponse {
+	response := HARResponse{
+		Status:      res.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(res.Status, fmt.Sprint(res.StatusCode))),
+		HTTPVersion: harHTTPVersion(res.Proto),
+		Cookies:     r.cookies(res.Cookies(), "Set-Cookie"),
+		Headers:     r.headers(res.Header),
+		RedirectURL: res.Header.Get("Location"),
+		HeadersSize: -1,
+		Content:     HARContent{MimeType: res.Header.Get("Content-Type")},
+	}
+
+	if response.StatusText == "" {
+		response.StatusText = http.StatusText(res.StatusCode)
+	}
+
+	if resBody == nil {
+		return response
+	}
+
+	response.BodySize = resBody.bytesRead()
+	text, encoding, size, comment := r.body(res.Header, resBody)
+	response.Content.Text, response.Content.Encoding, response.Content.Comment = text, encoding, comment
+	response.Content.Size = size
+
+	return response
+}
+
+func (r *HARRecorder) postData(header http.Header, reqBody *bodyCapture) *HARPostData {
+	text, encoding, _, comment := r.body(header, reqBody)
+	postData := &HARPostData{
+		MimeType: header.Get("Content-Type"),
+		Params:   []HARNameValue{},
+		Text:     text,
+		Comment:  comment,
+	}
+
+	if encoding != "" {
+		postData.Text = ""
+		postData.Comment = strings.TrimSpace("binary body omitted " + comment)
+		return postData
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(postData.MimeType); mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(text); err == nil {
+			for _, name := range slices.Sorted(maps.Keys(form)) {
+				for _, value := range form[name] {
+					postData.Params = append(postData.Params, HARNameValue{Name: name, Value: value})
+				}
+			}
+		}
+	}
+
+	return postData
+}
+
+// body returns the redacted text of a captured body, base64 encoded for binary content, with its
+// decompressed size and a comment when it was cut.
+func (r *HARRecorder) body(header http.Header, capture *bodyCapture) (string, string, int64, string) {
+	body := prepareBody(capture, header, r.redactor, r.config.maxBodySize)
+
+	var comment string
+	if body.truncated {
+		comment = fmt.Sprintf("truncated, %d bytes read", capture.bytesRead())
+	}
+
+	if body.binary {
+		return base64.StdEncoding.EncodeToString(body.data), "base64", body.size, comment
+	}
+
+	return string(body.data), "", body.size, comment
+}
+
+func (r *HARRecorder) headers(header http.Header) []HARNameValue {
+	sanitized := r.redactor.sanitizeHeaders(header)
+	headers := make([]HARNameValue, 0, len(sanitized))
+	for _, name := range slices.Sorted(maps.Keys(sanitized)) {
+		for _, value := range sanitized[name] {
+			headers = append(headers, HARNameValue{Name: name, Value: value})
+		}
+	}
+
+	return headers
+}
+
+// cookies lists cookies, with their values redacted when the header carrying them is.
+func (r *HARRecorder) cookies(cookies []*http.Cookie, header string) []HARCookie {
+	redacted := r.redactor.redactsHeader(header)
+	harCookies := make([]HARCookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		value := cookie.Value
+		if redacted {
+			value = redactedValue
+		}
+
+		harCookies = append(harCookies, HARCookie{Name: cookie.Name, Value: value})
+	}
+
+	return harCookies
+}
+
+// add keeps entry, flushing or dropping the oldest entry once maxEntries is reached.
+func (r *HARRecorder) add(entry HAREntry) {
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	full := r.config.maxEntries > 0 && len(r.entries) >= r.config.maxEntries
+	if full && !r.hasDestination() {
+		r.entries = slices.Delete(r.entries, 0, len(r.entries)-r.config.maxEntries)
+		full = false
+	}
+
+	// The full batch is taken under the lock, so concurrent requests never flush it twice.
+	var (
+		batch []HAREntry
+		seq   int
+	)
+	if full {
+		batch, seq = r.takeEntries()
+	}
+	r.mu.Unlock()
+
+	if full {
+		if err := r.write(batch, seq); err != nil {
+			r.config.logger.Warn("HAR flush failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// takeEntries removes the recorded entries and numbers the flush they go to. r.mu must be held.
+func (r *HARRecorder) takeEntries() ([]HAREntry, int) {
+	entries := r.entries
+	r.entries = nil
+	r.flushes++
+
+	return entries, r.flushes
+}
+
+func (r *HARRecorder) hasDestination() bool {
+	return r.config.writer != nil || r.config.file != ""
+}
+
+// Entries returns a copy of the entries recorded since the last flush.
+func (r *HARRecorder) Entries() []HAREntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.entries)
+}
+
+// HAR returns a document holding the entries recorded since the last flush.
+func (r *HARRecorder) HAR() *HAR {
+	return newHAR(r.Entries())
+}
+
+// WriteTo writes the entries recorded since the last flush as a HAR document, keeping them.
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	return r.HAR().WriteTo(w)
+}
+
+// Flush writes the recorded entries as one HAR document to the configured writer, or to a new
+// numbered file next to the configured path, then forgets them.
+func (r *HARRecorder) Flush() error {
+	if !r.hasDestination() {
+		return ErrHARNoDestination
+	}
+
+	r.mu.Lock()
+	entries, seq := r.takeEntries()
+	r.mu.Unlock()
+
+	return r.write(entries, seq)
+}
+
+// write writes entries as the HAR document of flush seq.
+func (r *HARRecorder) write(entries []HAREntry, seq int) error {
+	har := newHAR(entries)
+	if r.config.writer != nil {
+		r.writeMu.Lock()
+		defer r.writeMu.Unlock()
+
+		_, err := har.WriteTo(r.config.writer)
+		return err
+	}
+
+	ext := filepath.Ext(r.config.file)
+	path := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(r.config.file, ext), seq, ext)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := har.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func newHAR(entries []HAREntry) *HAR {
+	if entries == nil {
+		entries = []HAREntry{}
+	}
+
+	return &HAR{Log: HARLog{
+		Version: harVersion,
+		Creator: HARCreator{Name: harCreatorName, Version: harVersion},
+		Entries: entries,
+	}}
+}
+
+// WriteTo writes h as indented JSON.
+func (h *HAR) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	return io.Copy(w, bytes.NewReader(append(data, '\n')))
+}
+
+func harHTTPVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+
+	return proto
+}
+
+func harMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// total sums the timings that apply, SSL being part of Connect.
+func (t HARTimings) total() float64 {
+	var total float64
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		total += max(phase, 0)
+	}
+
+	return total
+}
+
+// harTimings maps the collected trace to HAR phases, Receive is left for the caller.
+func (t *RequestTiming) harTimings() HARTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	optional := func(d time.Duration) float64 {
+		if d == 0 {
+			return -1
+		}
+
+		return harMillis(d)
+	}
+
+	dns := span(t.dnsStart, t.dnsDone)
+	connect := span(t.connectStart, t.connectDone) + span(t.tlsStart, t.tlsDone)
+	return HARTimings{
+		Blocked: harMillis(max(span(t.start, t.gotConn)-dns-connect, 0)),
+		DNS:     optional(dns),
+		Connect: optional(connect),
+		SSL:     optional(span(t.tlsStart, t.tlsDone)),
+		Send:    harMillis(span(t.gotConn, t.wroteRequest)),
+		Wait:    harMillis(span(t.wroteRequest, t.firstByte)),
+	}
+}
@@ -0,0 +1,75 @@
+package transport
+
+import (
+	"io"
+	"log/slog"
+)
+
+type HAROption func(*harConfig) *harConfig
+
+// HAROptionMatcherConfig limits recording to matching paths and statuses, every exchange by default.
+func HAROptionMatcherConfig(config MatcherConfig) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.MatcherConfig = config
+		return c
+	}
+}
+
+// HAROptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func HAROptionMatcher(matcher Matcher) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// HAROptionRedactConfig replaces the redaction rules, shared with NewTransportLog, applied to entries.
+func HAROptionRedactConfig(config RedactConfig) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.RedactConfig = config
+		return c
+	}
+}
+
+// HAROptionMaxBodySize cuts recorded bodies longer than size bytes, 0 records them whole.
+func HAROptionMaxBodySize(size int) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.maxBodySize = size
+		return c
+	}
+}
+
+// HAROptionMaxEntries flushes the recorder once it holds max entries. Without a writer or file the
+// oldest entries are dropped instead.
+func HAROptionMaxEntries(max int) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.maxEntries = max
+		return c
+	}
+}
+
+// HAROptionWriter makes each flush write one HAR document to w.
+func HAROptionWriter(w io.Writer) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.writer = w
+		return c
+	}
+}
+
+// HAROptionFile makes each flush write a new file, path numbered per flush: traffic.har is written
+// as traffic-1.har, traffic-2.har and so on.
+func HAROptionFile(path string) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.file = path
+		return c
+	}
+}
+
+// HAROptionLogger receives the errors of flushes triggered by HAROptionMaxEntries.
+func HAROptionLogger(logger *slog.Logger) HAROption {
+	return func(c *harConfig) *harConfig {
+		c.logger = logger
+		return c
+	}
+}
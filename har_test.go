@@ -0,0 +1,188 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHARRecorder_Record(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1,"token":"secret"}`))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder()
+	client := &http.Client{Transport: NewTransportHAR(http.DefaultTransport, recorder)}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/login?api_key=k&page=2",
+		strings.NewReader("user=bob&password=hunter2"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer abc")
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	_, _ = io.ReadAll(res.Body)
+	require.NoError(t, res.Body.Close())
+
+	entries := recorder.Entries()
+	require.Len(t, entries, 1)
+	entry := entries[0]
+
+	require.Equal(t, server.URL+"/login?api_key=%5BREDACTED%5D&page=2", entry.Request.URL)
+	require.Equal(t, []HARNameValue{{Name: "api_key", Value: redactedValue}, {Name: "page", Value: "2"}}, entry.Request.QueryString)
+	require.Contains(t, entry.Request.Headers, HARNameValue{Name: "Authorization", Value: redactedValue})
+	require.Equal(t, "user=bob&password=[REDACTED]", entry.Request.PostData.Text)
+	require.Equal(t, []HARNameValue{{Name: "password", Value: redactedValue}, {Name: "user", Value: "bob"}}, entry.Request.PostData.Params)
+	require.Equal(t, int64(len("user=bob&password=hunter2")), entry.Request.BodySize)
+
+	require.Equal(t, http.StatusOK, entry.Response.Status)
+	require.Equal(t, "OK", entry.Response.StatusText)
+	require.Equal(t, []HARCookie{{Name: "session", Value: redactedValue}}, entry.Response.Cookies)
+	require.Equal(t, `{"id":1,"token":"[REDACTED]"}`, entry.Response.Content.Text)
+	require.Equal(t, "application/json", entry.Response.Content.MimeType)
+
+	require.Equal(t, "127.0.0.1", entry.ServerIPAddress)
+	require.GreaterOrEqual(t, entry.Timings.Wait, float64(0))
+	require.Equal(t, float64(-1), entry.Timings.SSL)
+	require.InDelta(t, entry.Timings.total(), entry.Time, 0.001)
+
+	var buf bytes.Buffer
+	_, err = recorder.WriteTo(&buf)
+	require.NoError(t, err)
+
+	var har HAR
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &har))
+	require.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 1)
+	require.Len(t, recorder.Entries(), 1, "WriteTo keeps the entries")
+}
+
+func TestHARRecorder_Failure(t *testing.T) {
+	recorder := NewHARRecorder()
+	client := &http.Client{
+		Transport: NewTransportHAR(&staticRoundTripper{err: errors.New("connection refused")}, recorder),
+	}
+
+	_, err := client.Get(defaultURL)
+	require.Error(t, err)
+
+	entries := recorder.Entries()
+	require.Len(t, entries, 1)
+	require.Equal(t, "connection refused", entries[0].Error)
+	require.Equal(t, 0, entries[0].Response.Status)
+	require.Equal(t, "http://example.com/v1/api?keyword=hhh&search=abc", entries[0].Request.URL)
+}
+
+func TestHARRecorder_Flush(t *testing.T) {
+	var out bytes.Buffer
+	recorder := NewHARRecorder(HAROptionWriter(&out), HAROptionMaxEntries(2))
+	client := &http.Client{
+		Transport: NewTransportHAR(&staticRoundTripper{status: http.StatusOK, body: "ok"}, recorder),
+	}
+
+	for range 3 {
+		res, err := client.Get(defaultURL)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	var har HAR
+	require.NoError(t, json.Unmarshal(out.Bytes(), &har))
+	require.Len(t, har.Log.Entries, 2)
+	require.Len(t, recorder.Entries(), 1)
+
+	require.ErrorIs(t, NewHARRecorder().Flush(), ErrHARNoDestination)
+}
+
+func TestHARRecorder_ConcurrentFlush(t *testing.T) {
+	var out bytes.Buffer
+	recorder := NewHARRecorder(HAROptionWriter(&out), HAROptionMaxEntries(2))
+	client := &http.Client{
+		Transport: NewTransportHAR(&staticRoundTripper{status: http.StatusOK, body: "ok"}, recorder),
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(defaultURL)
+			if err == nil {
+				_ = res.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	documents, entries := 0, 0
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var har HAR
+		require.NoError(t, dec.Decode(&har), "documents never interleave")
+		require.Len(t, har.Log.Entries, 2)
+		documents++
+		entries += len(har.Log.Entries)
+	}
+
+	require.Equal(t, 25, documents)
+	require.Equal(t, 50, entries)
+	require.Empty(t, recorder.Entries())
+}
+
+func TestHARRecorder_FlushFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.har")
+	recorder := NewHARRecorder(HAROptionFile(path))
+	client := &http.Client{
+		Transport: NewTransportHAR(&staticRoundTripper{status: http.StatusOK, body: "ok"}, recorder),
+	}
+
+	for range 2 {
+		res, err := client.Get(defaultURL)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.NoError(t, recorder.Flush())
+	}
+
+	for _, name := range []string{"traffic-1.har", "traffic-2.har"} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		require.NoError(t, err)
+
+		var har HAR
+		require.NoError(t, json.Unmarshal(data, &har))
+		require.Len(t, har.Log.Entries, 1)
+	}
+
+	require.Empty(t, recorder.Entries())
+}
+
+func TestHARRecorder_DropOldest(t *testing.T) {
+	recorder := NewHARRecorder(HAROptionMaxEntries(2))
+	client := &http.Client{
+		Transport: NewTransportHAR(&staticRoundTripper{status: http.StatusOK, body: "ok"}, recorder),
+	}
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		res, err := client.Get("http://example.com" + path)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	entries := recorder.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "http://example.com/b", entries[0].Request.URL)
+	require.Equal(t, "http://example.com/c", entries[1].Request.URL)
+}
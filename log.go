@@ -1,53 +1,74 @@
 package transport
 
 import (
-	"bytes"
-	"io"
+	"context"
 	"log/slog"
 	"net/http"
-	"slices"
-	"strings"
+	"net/url"
 	"time"
 )
 
 type logTransport struct {
-	tp      http.RoundTripper
-	config  *logConfig
-	matcher Matcher
-	logger  *slog.Logger
+	tp       http.RoundTripper
+	config   *logConfig
+	matcher  Matcher
+	redactor *redactor
+	routes   []pathTemplate
+	limiter  *logRateLimiter
+	logger   *slog.Logger
+
+	samplePatterns []pathPattern // Compiled patterns of config.sampleRoutes
 }
 
 type logConfig struct {
 	MatcherConfig
-	level               slog.Level
-	logHeaders          bool
-	logLatency          bool
-	logReqBody          bool
-	logResBody          bool
-	logQueryParams      bool     // Log query parameters separately
-	maxLogBodySize      int      // Max size for logging body 0 mean unlimit
-	redactSensitive     bool     // Redact sensitive headers like Authorization
-	redactSensitiveKeys []string // Redact sensitive headers like Authorization
-	statusLogLevels     map[int]slog.Level
-	logger              *slog.Logger
+	matcher Matcher // Replaces MatcherConfig when set
+	RedactConfig
+	level                slog.Level
+	logHeaders           bool
+	logLatency           bool
+	logReqBody           bool
+	logResBody           bool
+	logQueryParams       bool               // Log query parameters separately
+	maxLogBodySize       int                // Max size for logging body 0 mean unlimit
+	redactSensitive      bool               // Redact sensitive headers and body fields described by RedactConfig
+	statusLogLevels      map[int]slog.Level // Exact status codes, checked before statusRangeLevels
+	statusRangeLevels    []StatusLevel      // First matching range wins
+	pathTemplates        []string           // Logged paths matching a template are masked, e.g. /users/{id} logs /users/:id
+	bodyContentTypes     []string           // Only bodies of these media types are logged, empty for all
+	skipBodyContentTypes []string           // Bodies of these media types are never logged
+	prettyJSON           bool               // Indent JSON bodies instead of compacting them
+	binaryEncoding       BinaryEncoding     // Preview encoding for binary bodies
+	logResHeaders        bool               // Log response headers, redacted like request headers
+	logFullURL           bool               // Log scheme, host and redacted query instead of the path only
+	logConnInfo          bool               // Log remote and local addresses of the connection
+	logTiming            bool               // Log DNS, connect, TLS and time to first byte through httptrace
+	sampleRatio          float64            // Ratio of requests logged, decided per request ID
+	sampleRoutes         []SampleRoute      // Per-route ratios overriding sampleRatio, first match wins
+	alwaysLogErrors      bool               // Log failures and 5xx responses even when not sampled
+	slowThreshold        time.Duration      // Log requests at least this slow even when not sampled, 0 to disable
+	maxPerSecond         int                // Cap on entries per second, 0 for no cap
+	logger               *slog.Logger
+	observer             Observer
 }
 
 var defaultLogger = slog.Default()
 
 var DefaultLogConfig = logConfig{
-	MatcherConfig:       DefaultMatcherConfig,
-	level:               slog.LevelInfo,
-	logHeaders:          true,
-	logLatency:          true,
-	logReqBody:          true,
-	logResBody:          true,
-	maxLogBodySize:      0,
-	redactSensitive:     true,
-	redactSensitiveKeys: []string{"Authorization", "Cookie", "Set-Cookie"},
-	statusLogLevels: map[int]slog.Level{
-		500: slog.LevelError,
-		400: slog.LevelWarn,
-		200: slog.LevelInfo,
+	MatcherConfig:   DefaultMatcherConfig,
+	RedactConfig:    DefaultRedactConfig,
+	level:           slog.LevelInfo,
+	logHeaders:      true,
+	logLatency:      true,
+	logReqBody:      true,
+	logResBody:      true,
+	maxLogBodySize:  0,
+	redactSensitive: true,
+	sampleRatio:     1,
+	statusLogLevels: map[int]slog.Level{},
+	statusRangeLevels: []StatusLevel{
+		{StatusRange: StatusClass(5), Level: slog.LevelError},
+		{StatusRange: StatusClass(4), Level: slog.LevelWarn},
 	},
 	logger: defaultLogger,
 }
@@ -58,13 +79,33 @@ func NewTransportLog(tp http.RoundTripper, opts ...LogOption) http.RoundTripper
 		opt(&cfg)
 	}
 
-	cfg.redactSensitiveKeys = StringLowers(cfg.redactSensitiveKeys)
+	routes := make([]pathTemplate, len(cfg.pathTemplates))
+	for idx, template := range cfg.pathTemplates {
+		routes[idx] = parsePathTemplate(template)
+	}
+
+	samplePatterns := make([]pathPattern, len(cfg.sampleRoutes))
+	for idx, route := range cfg.sampleRoutes {
+		samplePatterns[idx] = compilePathPattern(route.Pattern)
+	}
+
+	var limiter *logRateLimiter
+	if cfg.maxPerSecond > 0 {
+		limiter = &logRateLimiter{limit: cfg.maxPerSecond, report: func(suppressed int) {
+			logSuppressed(context.Background(), cfg.logger, suppressed)
+		}}
+	}
 
 	return &logTransport{
-		tp:      tp,
-		config:  &cfg,
-		matcher: NewMatcher(cfg.MatcherConfig),
-		logger:  cfg.logger,
+		tp:       tp,
+		config:   &cfg,
+		matcher:  MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, cfg.logger, LayerLog),
+		redactor: newRedactor(cfg.RedactConfig),
+		routes:   routes,
+		limiter:  limiter,
+		logger:   cfg.logger,
+
+		samplePatterns: samplePatterns,
 	}
 }
 
@@ -73,64 +114,215 @@ func (lt *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		return lt.tp.RoundTrip(req)
 	}
 
-	start := time.Now()
-	logField := lt.buildLogRequestFields(req)
+	end := observeRequest(lt.config.observer, LayerLog, req)
+	res, err := lt.roundTrip(req)
+	end(res, err)
+
+	return res, err
+}
+
+func (lt *logTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	req, requestID := ensureRequestID(req)
+	if attemptCounterFromContext(req.Context()) == nil {
+		req, _ = withAttemptCounter(req)
+	}
+
+	sampled := lt.sampled(req, requestID)
+	if !sampled && !lt.needsOutcome() {
+		return lt.tp.RoundTrip(req)
+	}
+
+	ex := &logExchange{start: time.Now(), sampled: sampled}
+	req, ex.reqBody = lt.captureRequestBody(req)
+	req, ex.timing = lt.traceRequest(req)
+	ex.req = req
 
 	res, err := lt.tp.RoundTrip(req)
+	if !MatchOutcome(lt.matcher, req, newOutcome(res, err, time.Since(ex.start))) {
+		return res, err
+	}
+
 	if err != nil {
-		lt.logger.ErrorContext(req.Context(), "Request failed", slog.String("error", err.Error()))
+		lt.logFailure(ex, err)
 		return nil, err
 	}
 
-	if !lt.matcher.Match(req, res.StatusCode) {
+	level := lt.getLogLevel(res.StatusCode)
+	if !lt.enabled(req.Context(), level) {
+		return res, nil
+	}
+
+	logEntry := func(resBody *bodyCapture) {
+		latency := time.Since(ex.start)
+		if !ex.sampled && !lt.keepUnsampled(res.StatusCode, nil, latency) {
+			return
+		}
+
+		logField := lt.buildLogRequestFields(req, ex.reqBody)
+		logField = append(logField, lt.buildLogResponseFields(res, resBody, latency)...)
+		logField = append(logField, lt.buildLogTimingFields(ex.timing)...)
+		lt.emit(req.Context(), level, "HTTP Request", logField)
+	}
+
+	if res.Body == nil || res.Body == http.NoBody {
+		logEntry(nil)
 		return res, nil
 	}
 
-	logField = append(logField, lt.buildLogResponseFields(res, time.Since(start))...)
-	lt.logger.LogAttrs(req.Context(), lt.getLogLevel(res.StatusCode), "HTTP Request", logField...)
+	// The entry is emitted once the caller closes the body, so latency and size cover the transfer.
+	limit := captureLimit(lt.config.maxLogBodySize)
+	if !lt.config.logResBody {
+		limit = -1
+	}
+
+	res.Body = newBodyCapture(res.Body, limit, logEntry)
 
 	return res, nil
 }
 
-func (lt *logTransport) buildLogRequestFields(req *http.Request) []slog.Attr {
+// logExchange is the state of one logged request, gathered until its entry is emitted.
+type logExchange struct {
+	req     *http.Request
+	reqBody *bodyCapture
+	timing  *RequestTiming
+	start   time.Time
+	sampled bool
+}
+
+// logFailure logs a request the wrapped transport returned an error for, with the same request
+// fields as a completed exchange.
+func (lt *logTransport) logFailure(ex *logExchange, err error) {
+	req := ex.req
+	latency := time.Since(ex.start)
+	if !lt.enabled(req.Context(), slog.LevelError) {
+		return
+	}
+
+	if !ex.sampled && !lt.keepUnsampled(0, err, latency) {
+		return
+	}
+
+	logField := lt.buildLogRequestFields(req, ex.reqBody)
+	logField = append(logField,
+		slog.String("error", err.Error()),
+		slog.String("error_class", string(ClassifyError(err))),
+	)
+
+	if state := contextState(req.Context()); state != "" {
+		logField = append(logField, slog.String("context", state))
+	}
+
+	if lt.config.logLatency {
+		logField = append(logField, slog.Duration("latency", latency))
+	}
+
+	logField = append(logField, lt.buildLogTimingFields(ex.timing)...)
+	lt.emit(req.Context(), slog.LevelError, "Request failed", logField)
+}
+
+// emit writes an entry unless the per-second cap is reached. The first entry of a new second is
+// preceded by a summary of the entries suppressed before it, unless the limiter flushed it already.
+func (lt *logTransport) emit(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr) {
+	if lt.limiter != nil {
+		allowed, suppressed := lt.limiter.allow(time.Now())
+		if suppressed > 0 {
+			logSuppressed(ctx, lt.logger, suppressed)
+		}
+
+		if !allowed {
+			return
+		}
+	}
+
+	lt.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logSuppressed writes the summary of entries dropped by the per-second cap.
+func logSuppressed(ctx context.Context, logger *slog.Logger, suppressed int) {
+	logger.LogAttrs(ctx, slog.LevelWarn, "Log entries suppressed", slog.Int("suppressed", suppressed))
+}
+
+func (lt *logTransport) buildLogTimingFields(timing *RequestTiming) []slog.Attr {
+	var fields []slog.Attr
+	if lt.config.logConnInfo {
+		fields = append(fields, slog.Attr{Key: "conn", Value: timing.connLogValue()})
+	}
+
+	if lt.config.logTiming {
+		fields = append(fields, slog.Any("timing", timing))
+	}
+
+	return fields
+}
+
+// captureRequestBody returns a shallow copy of req whose body keeps the first bytes the wrapped
+// transport reads from it.
+func (lt *logTransport) captureRequestBody(req *http.Request) (*http.Request, *bodyCapture) {
+	if !lt.config.logReqBody || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	reqBody := newBodyCapture(req.Body, captureLimit(lt.config.maxLogBodySize), nil)
+	req = req.WithContext(req.Context())
+	req.Body = reqBody
+
+	return req, reqBody
+}
+
+func (lt *logTransport) buildLogRequestFields(req *http.Request, reqBody *bodyCapture) []slog.Attr {
 	fields := lt.commonFields(req)
 
 	if lt.config.logQueryParams {
-		fields = append(fields, slog.Any("query_params", req.URL.Query()))
+		fields = append(fields, slog.Any("query_params", lt.sanitizeQuery(req.URL.Query())))
 	}
 
 	if lt.config.logHeaders {
 		fields = append(fields, slog.Any("headers", lt.sanitizeHeaders(req.Header)))
 	}
 
-	if lt.config.logReqBody && req.Body != nil {
-		body, _ := io.ReadAll(req.Body)
-		req.Body = io.NopCloser(bytes.NewBuffer(body))
-		if lt.config.maxLogBodySize != 0 && len(body) > lt.config.maxLogBodySize {
-			fields = append(fields, slog.String("req_body", string(body[:lt.config.maxLogBodySize])+"..."))
-		} else {
-			fields = append(fields, slog.String("req_body", string(body)))
+	requestFields := []slog.Attr{slog.Int64("content_length", req.ContentLength)}
+	if reqBody != nil {
+		requestFields = append(requestFields, slog.Int64("bytes", reqBody.bytesRead()))
+	}
+
+	fields = append(fields, slog.Attr{Key: "request", Value: slog.GroupValue(requestFields...)})
+
+	if reqBody != nil {
+		body, truncated := reqBody.captured()
+		if value, ok := lt.formatBody(req.Header, body, truncated); ok {
+			fields = append(fields, slog.Attr{Key: "req_body", Value: value})
 		}
 	}
 
 	return fields
 }
 
-func (lt *logTransport) buildLogResponseFields(res *http.Response, latency time.Duration) []slog.Attr {
-	fields := lt.commonFields(res.Request)
-	fields = append(fields, slog.Int("status", res.StatusCode))
+func (lt *logTransport) buildLogResponseFields(res *http.Response, resBody *bodyCapture, latency time.Duration) []slog.Attr {
+	fields := []slog.Attr{slog.Int("status", res.StatusCode)}
 
 	if lt.config.logLatency {
 		fields = append(fields, slog.Duration("latency", latency))
 	}
 
-	if lt.config.logResBody && res.Body != nil {
-		body, _ := io.ReadAll(res.Body)
-		res.Body = io.NopCloser(bytes.NewBuffer(body))
-		if lt.config.maxLogBodySize != 0 && len(body) > lt.config.maxLogBodySize {
-			fields = append(fields, slog.String("res_body", string(body[:lt.config.maxLogBodySize])+"..."))
-		} else {
-			fields = append(fields, slog.String("res_body", string(body)))
+	if resBody != nil {
+		fields = append(fields, slog.Int64("res_bytes", resBody.bytesRead()))
+	}
+
+	responseFields := []slog.Attr{
+		slog.String("proto", res.Proto),
+		slog.Int64("content_length", res.ContentLength),
+	}
+
+	if lt.config.logResHeaders {
+		responseFields = append(responseFields, slog.Any("headers", lt.sanitizeHeaders(res.Header)))
+	}
+
+	fields = append(fields, slog.Attr{Key: "response", Value: slog.GroupValue(responseFields...)})
+
+	if lt.config.logResBody && resBody != nil {
+		body, truncated := resBody.captured()
+		if value, ok := lt.formatBody(res.Header, body, truncated); ok {
+			fields = append(fields, slog.Attr{Key: "res_body", Value: value})
 		}
 	}
 
@@ -140,33 +332,78 @@ func (lt *logTransport) buildLogResponseFields(res *http.Response, latency time.
 func (lt *logTransport) commonFields(req *http.Request) []slog.Attr {
 	fields := []slog.Attr{
 		slog.String("method", req.Method),
-		slog.String("url", req.URL.Path),
-		slog.String("remote_addr", req.RemoteAddr),
+		slog.String("url", lt.logURL(req.URL)),
 	}
 
-	if reqID := req.Header.Get("X-Request-ID"); reqID != "" {
+	if reqID := RequestIDFromContext(req.Context()); reqID != "" {
+		fields = append(fields, slog.String("request_id", reqID))
+	} else if reqID := req.Header.Get(HeaderRequestID); reqID != "" {
 		fields = append(fields, slog.String("request_id", reqID))
 	}
 
-	if traceID := req.Header.Get("X-Trace-ID"); traceID != "" {
+	// Inside the retry transport each entry is one attempt; outside it, one entry covers all of them.
+	if attempt, ok := AttemptFromContext(req.Context()); ok {
+		fields = append(fields, slog.Int("attempt", attempt.Number), slog.Int("max_attempts", attempt.Max))
+	} else if counter := attemptCounterFromContext(req.Context()); counter != nil {
+		fields = append(fields, slog.Int("attempts", counter.attempts()))
+	}
+
+	if tc, ok := TraceContextFromContext(req.Context()); ok {
+		fields = append(fields, slog.String("trace_id", tc.TraceIDString()), slog.String("span_id", tc.SpanIDString()))
+	} else if tc, ok := TraceContextFromHeader(req.Header); ok {
+		fields = append(fields, slog.String("trace_id", tc.TraceIDString()), slog.String("span_id", tc.SpanIDString()))
+	} else if traceID := req.Header.Get("X-Trace-ID"); traceID != "" {
 		fields = append(fields, slog.String("trace_id", traceID))
 	}
 
 	return fields
 }
 
-// getLogLevel returns the appropriate log level for the status code
+// logURL returns the path masked by the configured templates, or the full redacted URL when enabled.
+func (lt *logTransport) logURL(u *url.URL) string {
+	path := normalizeRoute(lt.routes, u.Path)
+	if !lt.config.logFullURL {
+		return path
+	}
+
+	full := *u
+	if lt.config.redactSensitive {
+		full = *lt.redactor.redactURL(u)
+	}
+
+	full.Path, full.RawPath, full.Fragment = path, "", ""
+
+	return full.String()
+}
+
+// traceRequest attaches a RequestTiming when connection info or timings are logged.
+func (lt *logTransport) traceRequest(req *http.Request) (*http.Request, *RequestTiming) {
+	if !lt.config.logConnInfo && !lt.config.logTiming {
+		return req, nil
+	}
+
+	return WithRequestTiming(req)
+}
+
+// getLogLevel returns the appropriate log level for the status code.
+// Statuses without a mapping are logged at the configured level.
 func (lt *logTransport) getLogLevel(status int) slog.Level {
 	if level, exists := lt.config.statusLogLevels[status]; exists {
 		return level
 	}
-	if status >= 500 {
-		return slog.LevelError
-	}
-	if status >= 400 {
-		return slog.LevelWarn
+
+	for _, sl := range lt.config.statusRangeLevels {
+		if sl.Contains(status) {
+			return sl.Level
+		}
 	}
-	return slog.LevelInfo
+
+	return lt.config.level
+}
+
+// enabled reports whether an entry at level passes both the configured minimum level and the logger.
+func (lt *logTransport) enabled(ctx context.Context, level slog.Level) bool {
+	return level >= lt.config.level && lt.logger.Enabled(ctx, level)
 }
 
 func (lt *logTransport) sanitizeHeaders(headers http.Header) map[string][]string {
@@ -174,14 +411,13 @@ func (lt *logTransport) sanitizeHeaders(headers http.Header) map[string][]string
 		return headers
 	}
 
-	sensitiveKeys := lt.config.redactSensitiveKeys
-	sanitized := make(map[string][]string)
-	for key, values := range headers {
-		if slices.Contains(sensitiveKeys, strings.ToLower(key)) {
-			sanitized[key] = []string{"[REDACTED]"}
-		} else {
-			sanitized[key] = values
-		}
+	return lt.redactor.sanitizeHeaders(headers)
+}
+
+func (lt *logTransport) sanitizeQuery(query url.Values) url.Values {
+	if !lt.config.redactSensitive {
+		return query
 	}
-	return sanitized
+
+	return lt.redactor.redactQuery(query)
 }
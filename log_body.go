@@ -0,0 +1,233 @@
+package transport
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BinaryEncoding selects how binary bodies are previewed in logs.
+type BinaryEncoding string
+
+const (
+	BinaryEncodingNone   BinaryEncoding = ""       // Log only the content type of binary bodies
+	BinaryEncodingBase64 BinaryEncoding = "base64" // Log a base64 preview of binary bodies
+	BinaryEncodingHex    BinaryEncoding = "hex"    // Log a hex preview of binary bodies
+)
+
+const (
+	binaryPreviewSize      = 64
+	maxDecompressedLogBody = 1 << 20
+	maxMultipartValueSize  = 256
+)
+
+type bodyKind int
+
+const (
+	bodyKindText bodyKind = iota
+	bodyKindJSON
+	bodyKindForm
+	bodyKindMultipart
+	bodyKindBinary
+)
+
+func classifyMediaType(mediaType string) bodyKind {
+	switch {
+	case strings.Contains(mediaType, "json"):
+		return bodyKindJSON
+	case mediaType == "application/x-www-form-urlencoded":
+		return bodyKindForm
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return bodyKindMultipart
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "xml"),
+		mediaType == "application/javascript",
+		mediaType == "application/graphql":
+		return bodyKindText
+	default:
+		return bodyKindBinary
+	}
+}
+
+// matchesMediaType matches a media type against patterns like application/json or image/*.
+func matchesMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+
+		if pattern == ConsCharStar || pattern == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatBody renders a captured body for logging according to its content type.
+// It reports false when the content type is excluded from body logging.
+func (lt *logTransport) formatBody(header http.Header, body []byte, truncated bool) (slog.Value, bool) {
+	contentType := header.Get("Content-Type")
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" && len(body) > 0 {
+		mediaType, params, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+
+	if len(lt.config.bodyContentTypes) > 0 && !matchesMediaType(lt.config.bodyContentTypes, mediaType) {
+		return slog.Value{}, false
+	}
+
+	if matchesMediaType(lt.config.skipBodyContentTypes, mediaType) {
+		return slog.Value{}, false
+	}
+
+	body, truncated = decompressBody(header.Get("Content-Encoding"), body, truncated)
+
+	switch classifyMediaType(mediaType) {
+	case bodyKindMultipart:
+		return lt.formatMultipartBody(params["boundary"], body), true
+	case bodyKindForm:
+		return lt.formatFormBody(contentType, body), true
+	case bodyKindBinary:
+		return lt.formatBinaryBody(mediaType, body), true
+	}
+
+	if lt.config.redactSensitive {
+		body = lt.redactor.redactBody(contentType, body)
+	}
+
+	if classifyMediaType(mediaType) == bodyKindJSON {
+		body = lt.reformatJSON(body)
+	}
+
+	if lt.config.maxLogBodySize != 0 && len(body) > lt.config.maxLogBodySize {
+		body, truncated = body[:lt.config.maxLogBodySize], true
+	}
+
+	if truncated {
+		return slog.StringValue(string(body) + "..."), true
+	}
+
+	return slog.StringValue(string(body)), true
+}
+
+// decompressBody inflates gzip or deflate bodies for display. A truncated body is inflated as far as possible.
+func decompressBody(contentEncoding string, body []byte, truncated bool) ([]byte, bool) {
+	var (
+		r   io.Reader
+		err error
+	)
+
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r = flate.NewReader(bytes.NewReader(body))
+	default:
+		return body, truncated
+	}
+
+	if err != nil {
+		return body, truncated
+	}
+
+	var out bytes.Buffer
+	n, err := io.Copy(&out, io.LimitReader(r, maxDecompressedLogBody+1))
+	if n > maxDecompressedLogBody {
+		return out.Bytes()[:maxDecompressedLogBody], true
+	}
+
+	return out.Bytes(), truncated || err != nil
+}
+
+// reformatJSON renders JSON compact or indented. Bodies that are not complete JSON are left unchanged.
+func (lt *logTransport) reformatJSON(body []byte) []byte {
+	var out bytes.Buffer
+	var err error
+	if lt.config.prettyJSON {
+		err = json.Indent(&out, body, "", "  ")
+	} else {
+		err = json.Compact(&out, body)
+	}
+
+	if err != nil {
+		return body
+	}
+
+	return out.Bytes()
+}
+
+func (lt *logTransport) formatFormBody(contentType string, body []byte) slog.Value {
+	if lt.config.redactSensitive {
+		body = lt.redactor.redactBody(contentType, body)
+	}
+
+	values, _ := url.ParseQuery(string(body))
+
+	return slog.AnyValue(values)
+}
+
+// formatMultipartBody summarizes each part by field name, file name, content type and size.
+// Values of small non-file fields are included unless redacted.
+func (lt *logTransport) formatMultipartBody(boundary string, body []byte) slog.Value {
+	if boundary == "" {
+		return slog.StringValue("")
+	}
+
+	var parts []any
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+
+		var content bytes.Buffer
+		size, _ := io.Copy(&content, part)
+		summary := map[string]any{
+			"name": part.FormName(),
+			"size": size,
+		}
+
+		if contentType := part.Header.Get("Content-Type"); contentType != "" {
+			summary["content_type"] = contentType
+		}
+
+		if fileName := part.FileName(); fileName != "" {
+			summary["filename"] = fileName
+		} else if size <= maxMultipartValueSize {
+			summary["value"] = content.String()
+			if lt.config.redactSensitive {
+				summary["value"] = lt.redactor.redactPart(part.FormName(), part.Header.Get("Content-Type"), content.Bytes())
+			}
+		}
+
+		parts = append(parts, summary)
+	}
+
+	return slog.AnyValue(parts)
+}
+
+func (lt *logTransport) formatBinaryBody(mediaType string, body []byte) slog.Value {
+	attrs := []slog.Attr{slog.String("content_type", mediaType)}
+
+	preview := body[:min(len(body), binaryPreviewSize)]
+	switch lt.config.binaryEncoding {
+	case BinaryEncodingBase64:
+		attrs = append(attrs, slog.String("base64", base64.StdEncoding.EncodeToString(preview)))
+	case BinaryEncodingHex:
+		attrs = append(attrs, slog.String("hex", hex.EncodeToString(preview)))
+	}
+
+	return slog.GroupValue(attrs...)
+}
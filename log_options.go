@@ -1,9 +1,14 @@
 package transport
 
-import "log/slog"
+import (
+	"log/slog"
+	"regexp"
+	"time"
+)
 
 type LogOption func(*logConfig) *logConfig
 
+// LogOptionLevel sets the level for successful exchanges and the minimum level an entry needs to be emitted.
 func LogOptionLevel(level slog.Level) LogOption {
 	return func(c *logConfig) *logConfig {
 		c.level = level
@@ -69,7 +74,47 @@ func LogOptionRedactSensitive(enable bool) LogOption {
 
 func LogOptionRedactSensitiveKeys(keys []string) LogOption {
 	return func(c *logConfig) *logConfig {
-		c.redactSensitiveKeys = keys
+		c.Headers = keys
+		return c
+	}
+}
+
+func LogOptionRedactConfig(config RedactConfig) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.RedactConfig = config
+		return c
+	}
+}
+
+// LogOptionRedactBodyKeys sets the JSON keys redacted at any depth, case-insensitive.
+func LogOptionRedactBodyKeys(keys []string) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.BodyKeys = keys
+		return c
+	}
+}
+
+// LogOptionRedactBodyPaths sets JSONPath-like selectors to redact, e.g. $.user.password or $.cards[*].number.
+func LogOptionRedactBodyPaths(paths []string) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.BodyPaths = paths
+		return c
+	}
+}
+
+// LogOptionRedactFormFields sets the form-encoded field names to redact, case-insensitive.
+func LogOptionRedactFormFields(fields []string) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.FormFields = fields
+		return c
+	}
+}
+
+// LogOptionRedactBodyPatterns sets regular expressions whose matches are redacted from bodies,
+// e.g. RedactPatternCardNumber or RedactPatternEmail.
+func LogOptionRedactBodyPatterns(patterns []*regexp.Regexp) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.BodyPatterns = patterns
 		return c
 	}
 }
@@ -87,3 +132,151 @@ func LogOptionMatcherConfig(config MatcherConfig) LogOption {
 		return c
 	}
 }
+
+// LogOptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func LogOptionMatcher(matcher Matcher) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// LogOptionStatusRangeLogLevels sets the log levels for status ranges, e.g. StatusClass(5) or {From: 500, To: 599}.
+func LogOptionStatusRangeLogLevels(levels []StatusLevel) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.statusRangeLevels = levels
+		return c
+	}
+}
+
+// LogOptionRedactQueryParams sets the query parameter names to redact, case-insensitive.
+func LogOptionRedactQueryParams(params []string) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.QueryParams = params
+		return c
+	}
+}
+
+// LogOptionPathTemplates masks logged paths matching a template, e.g. /users/{id} logs /users/:id.
+// Templates are matched like the paths of MatcherConfig patterns.
+func LogOptionPathTemplates(templates []string) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.pathTemplates = templates
+		return c
+	}
+}
+
+// LogOptionBodyContentTypes logs only bodies whose media type matches, e.g. application/json or text/*.
+func LogOptionBodyContentTypes(contentTypes []string) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.bodyContentTypes = contentTypes
+		return c
+	}
+}
+
+// LogOptionSkipBodyContentTypes never logs bodies whose media type matches, e.g. image/*.
+func LogOptionSkipBodyContentTypes(contentTypes []string) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.skipBodyContentTypes = contentTypes
+		return c
+	}
+}
+
+func LogOptionPrettyJSON(enable bool) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.prettyJSON = enable
+		return c
+	}
+}
+
+func LogOptionBinaryEncoding(encoding BinaryEncoding) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.binaryEncoding = encoding
+		return c
+	}
+}
+
+// LogOptionResHeaders logs response headers, redacted like request headers.
+func LogOptionResHeaders(enable bool) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.logResHeaders = enable
+		return c
+	}
+}
+
+// LogOptionFullURL logs the scheme, host and redacted query along with the path.
+func LogOptionFullURL(enable bool) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.logFullURL = enable
+		return c
+	}
+}
+
+// LogOptionConnInfo logs the remote IP and local address of the connection each request used.
+func LogOptionConnInfo(enable bool) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.logConnInfo = enable
+		return c
+	}
+}
+
+// LogOptionTiming logs a timing group with DNS lookup, TCP connect, TLS handshake, time to first byte
+// and connection reuse, collected through httptrace.
+func LogOptionTiming(enable bool) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.logTiming = enable
+		return c
+	}
+}
+
+// LogOptionSampleRatio logs only the given ratio of requests. The decision is derived from the request ID,
+// so stacked log transports agree on it.
+func LogOptionSampleRatio(ratio float64) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.sampleRatio = ratio
+		return c
+	}
+}
+
+// LogOptionSampleRoutes sets per-route sampling ratios, matched like MatcherConfig paths.
+func LogOptionSampleRoutes(routes []SampleRoute) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.sampleRoutes = routes
+		return c
+	}
+}
+
+// LogOptionAlwaysLogErrors logs failed requests and 5xx responses regardless of sampling.
+func LogOptionAlwaysLogErrors(enable bool) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.alwaysLogErrors = enable
+		return c
+	}
+}
+
+// LogOptionSlowThreshold logs requests taking at least threshold regardless of sampling.
+func LogOptionSlowThreshold(threshold time.Duration) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.slowThreshold = threshold
+		return c
+	}
+}
+
+// LogOptionMaxPerSecond caps the entries emitted per second. Suppressed entries are counted and
+// reported by a summary entry, at the start of the next second with traffic or at most a second
+// after the first of them.
+func LogOptionMaxPerSecond(max int) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.maxPerSecond = max
+		return c
+	}
+}
+
+// LogOptionObserver reports request start and end events of the log transport to observer.
+func LogOptionObserver(observer Observer) LogOption {
+	return func(c *logConfig) *logConfig {
+		c.observer = observer
+		return c
+	}
+}
@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SampleRoute sets the sampling ratio of requests whose METHOD|path matches Pattern, as in MatcherConfig.
+type SampleRoute struct {
+	Pattern string
+	Ratio   float64
+}
+
+// sampleRatio returns the ratio of the first matching route, or the default ratio.
+func (lt *logTransport) sampleRatio(req *http.Request) float64 {
+	if len(lt.config.sampleRoutes) == 0 {
+		return lt.config.sampleRatio
+	}
+
+	for idx, route := range lt.config.sampleRoutes {
+		if lt.samplePatterns[idx].match(req.Method, req.URL.Path) {
+			return route.Ratio
+		}
+	}
+
+	return lt.config.sampleRatio
+}
+
+// sampled decides whether a request is logged. The decision is a pure function of the request ID,
+// so every layer sharing the ID makes the same choice.
+func (lt *logTransport) sampled(req *http.Request, requestID string) bool {
+	ratio := lt.sampleRatio(req)
+	if ratio >= 1 {
+		return true
+	}
+
+	if ratio <= 0 {
+		return false
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(requestID))
+
+	return float64(h.Sum64())/math.MaxUint64 < ratio
+}
+
+// keepUnsampled reports whether an exchange that was not sampled is logged anyway.
+func (lt *logTransport) keepUnsampled(status int, err error, latency time.Duration) bool {
+	if lt.config.alwaysLogErrors && (err != nil || status >= http.StatusInternalServerError) {
+		return true
+	}
+
+	return lt.config.slowThreshold > 0 && latency >= lt.config.slowThreshold
+}
+
+// needsOutcome reports whether unsampled requests still have to be observed to apply keepUnsampled.
+func (lt *logTransport) needsOutcome() bool {
+	return lt.config.alwaysLogErrors || lt.config.slowThreshold > 0
+}
+
+// logRateLimiter caps the entries emitted per second and counts the rest.
+type logRateLimiter struct {
+	limit  int
+	report func(suppressed int) // Called with the count of a burst once no entry reported it, may be nil
+
+	mu         sync.Mutex
+	window     int64
+	count      int
+	suppressed int
+	armed      bool // A flush of the suppressed count is scheduled
+}
+
+// allow reports whether an entry may be emitted now. When it opens a new second it also returns
+// how many entries were suppressed since the last summary. The first suppressed entry schedules a
+// flush one second later, so the count of a burst is reported even when no entry follows it.
+func (l *logRateLimiter) allow(now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var suppressed int
+	if window := now.Unix(); window != l.window {
+		l.window, l.count = window, 0
+		suppressed, l.suppressed = l.suppressed, 0
+	}
+
+	if l.count >= l.limit {
+		l.suppressed++
+		if l.report != nil && !l.armed {
+			l.armed = true
+			time.AfterFunc(time.Second, l.flush)
+		}
+
+		return false, suppressed
+	}
+
+	l.count++
+
+	return true, suppressed
+}
+
+// flush reports the entries suppressed since the last summary, if an entry did not already.
+func (l *logRateLimiter) flush() {
+	l.mu.Lock()
+	suppressed := l.suppressed
+	l.suppressed, l.armed = 0, false
+	l.mu.Unlock()
+
+	if suppressed > 0 {
+		l.report(suppressed)
+	}
+}
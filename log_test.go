@@ -0,0 +1,744 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testLogHandler records every emitted entry so tests can assert on levels and attributes.
+type testLogHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *testLogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *testLogHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+func (h *testLogHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *testLogHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func (h *testLogHandler) levels() []slog.Level {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	levels := make([]slog.Level, len(h.records))
+	for idx, r := range h.records {
+		levels[idx] = r.Level
+	}
+
+	return levels
+}
+
+// attrs flattens the attributes of the i-th record, joining group keys with dots.
+func (h *testLogHandler) attrs(i int) map[string]slog.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ans := map[string]slog.Value{}
+	h.records[i].Attrs(func(a slog.Attr) bool {
+		flattenAttr(ans, "", a)
+		return true
+	})
+
+	return ans
+}
+
+func flattenAttr(dst map[string]slog.Value, prefix string, a slog.Attr) {
+	key := prefix + a.Key
+	value := a.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		dst[key] = value
+		return
+	}
+
+	for _, ga := range value.Group() {
+		flattenAttr(dst, key+".", ga)
+	}
+}
+
+type staticRoundTripper struct {
+	status int
+	body   string
+	err    error
+}
+
+func (s *staticRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &http.Response{
+		StatusCode: s.status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+var logAllMatcherConfig = MatcherConfig{
+	OnStatus:       []int{NumberZero},
+	WhiteListPaths: []string{ConsCharStar},
+}
+
+type testLogLevelCase struct {
+	name           string
+	statuses       []int
+	options        []LogOption
+	expectedLevels []slog.Level
+}
+
+func TestLogTransport_Levels(t *testing.T) {
+	testCases := []*testLogLevelCase{
+		defaultLevels,
+		configuredSuccessLevel,
+		minimumLevelFilter,
+		customStatusRanges,
+		exactStatusOverridesRange,
+	}
+
+	for _, test := range testCases {
+		handler := &testLogHandler{}
+		options := append([]LogOption{
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+		}, test.options...)
+
+		for _, status := range test.statuses {
+			client := &http.Client{
+				Transport: NewTransportLog(&staticRoundTripper{status: status}, options...),
+			}
+
+			res, err := client.Get(defaultURL)
+			require.NoError(t, err, test.name)
+			require.NoError(t, res.Body.Close(), test.name)
+		}
+
+		require.Equal(t, test.expectedLevels, handler.levels(), test.name)
+	}
+}
+
+var defaultLevels = &testLogLevelCase{
+	name:           "defaultLevels",
+	statuses:       []int{http.StatusOK, http.StatusFound, http.StatusNotFound, http.StatusServiceUnavailable, 599},
+	expectedLevels: []slog.Level{slog.LevelInfo, slog.LevelInfo, slog.LevelWarn, slog.LevelError, slog.LevelError},
+}
+
+var configuredSuccessLevel = &testLogLevelCase{
+	name:           "configuredSuccessLevel",
+	statuses:       []int{http.StatusOK, http.StatusInternalServerError},
+	options:        []LogOption{LogOptionLevel(slog.LevelDebug)},
+	expectedLevels: []slog.Level{slog.LevelDebug, slog.LevelError},
+}
+
+var minimumLevelFilter = &testLogLevelCase{
+	name:     "minimumLevelFilter",
+	statuses: []int{http.StatusOK, http.StatusNotFound, http.StatusBadGateway},
+	options: []LogOption{
+		LogOptionLevel(slog.LevelInfo),
+		LogOptionStatusRangeLogLevels([]StatusLevel{
+			{StatusRange: StatusClass(4), Level: slog.LevelDebug},
+			{StatusRange: StatusClass(5), Level: slog.LevelError},
+		}),
+	},
+	expectedLevels: []slog.Level{slog.LevelInfo, slog.LevelError},
+}
+
+var customStatusRanges = &testLogLevelCase{
+	name:     "customStatusRanges",
+	statuses: []int{http.StatusOK, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	options: []LogOption{
+		LogOptionStatusRangeLogLevels([]StatusLevel{
+			{StatusRange: StatusRange{From: 429, To: 429}, Level: slog.LevelError},
+			{StatusRange: StatusRange{From: 500, To: 599}, Level: slog.LevelWarn},
+		}),
+	},
+	expectedLevels: []slog.Level{slog.LevelInfo, slog.LevelError, slog.LevelWarn},
+}
+
+var exactStatusOverridesRange = &testLogLevelCase{
+	name:     "exactStatusOverridesRange",
+	statuses: []int{http.StatusNotImplemented, http.StatusServiceUnavailable},
+	options: []LogOption{
+		LogOptionStatusLogLevels(map[int]slog.Level{http.StatusNotImplemented: slog.LevelWarn}),
+	},
+	expectedLevels: []slog.Level{slog.LevelWarn, slog.LevelError},
+}
+
+type testLogFieldsCase struct {
+	name     string
+	url      string
+	options  []LogOption
+	expected map[string]any
+}
+
+func TestLogTransport_Fields(t *testing.T) {
+	testCases := []*testLogFieldsCase{
+		redactedQueryParams,
+		maskedPathTemplate,
+		unmatchedPathTemplate,
+	}
+
+	for _, test := range testCases {
+		handler := &testLogHandler{}
+		options := append([]LogOption{
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+		}, test.options...)
+		client := &http.Client{
+			Transport: NewTransportLog(&staticRoundTripper{status: http.StatusOK}, options...),
+		}
+
+		res, err := client.Get(test.url)
+		require.NoError(t, err, test.name)
+		require.NoError(t, res.Body.Close(), test.name)
+
+		attrs := handler.attrs(0)
+		for key, expected := range test.expected {
+			require.Contains(t, attrs, key, test.name)
+			require.Equal(t, expected, attrs[key].Any(), test.name, key)
+		}
+	}
+}
+
+var redactedQueryParams = &testLogFieldsCase{
+	name:    "redactedQueryParams",
+	url:     defaultDomain + defaultPath + "?api_key=secret&sig=abc&q=go",
+	options: []LogOption{LogOptionQueryParams(true)},
+	expected: map[string]any{
+		"query_params": url.Values{"api_key": {"[REDACTED]"}, "sig": {"[REDACTED]"}, "q": {"go"}},
+	},
+}
+
+var maskedPathTemplate = &testLogFieldsCase{
+	name:     "maskedPathTemplate",
+	url:      defaultDomain + "/users/42/orders/7",
+	options:  []LogOption{LogOptionPathTemplates([]string{"/users/{id}", "/users/{id}/orders/{orderID}"})},
+	expected: map[string]any{"url": "/users/:id/orders/:orderID"},
+}
+
+var unmatchedPathTemplate = &testLogFieldsCase{
+	name:     "unmatchedPathTemplate",
+	url:      defaultDomain + "/accounts/42",
+	options:  []LogOption{LogOptionPathTemplates([]string{"/users/{id}"})},
+	expected: map[string]any{"url": "/accounts/42"},
+}
+
+type streamRoundTripper struct {
+	size int
+}
+
+func (s *streamRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       io.NopCloser(io.LimitReader(infiniteReader{}, int64(s.size))),
+		Request:    req,
+	}, nil
+}
+
+type infiniteReader struct{}
+
+func (infiniteReader) Read(p []byte) (int, error) {
+	for idx := range p {
+		p[idx] = 'a'
+	}
+
+	return len(p), nil
+}
+
+func TestLogTransport_StreamingBody(t *testing.T) {
+	handler := &testLogHandler{}
+	client := &http.Client{
+		Transport: NewTransportLog(&streamRoundTripper{size: 1 << 20},
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+			LogOptionMaxLogBodySize(8),
+		),
+	}
+
+	res, err := client.Post(defaultURL, "text/plain", strings.NewReader("request body"))
+	require.NoError(t, err)
+	require.Empty(t, handler.levels(), "logged before the body was consumed")
+
+	n, err := io.Copy(io.Discard, res.Body)
+	require.NoError(t, err)
+	require.EqualValues(t, 1<<20, n)
+	require.NoError(t, res.Body.Close())
+	require.NoError(t, res.Body.Close())
+
+	require.Len(t, handler.levels(), 1)
+	attrs := handler.attrs(0)
+	require.Equal(t, "aaaaaaaa...", attrs["res_body"].String())
+	require.EqualValues(t, 1<<20, attrs["res_bytes"].Int64())
+	require.Equal(t, "request ...", attrs["req_body"].String())
+}
+
+type testLogBodyCase struct {
+	name     string
+	header   http.Header
+	body     []byte
+	options  []LogOption
+	expected any
+	omitted  bool
+}
+
+func TestLogTransport_BodyFormats(t *testing.T) {
+	testCases := []*testLogBodyCase{
+		compactJSONBody,
+		prettyJSONBody,
+		formBody,
+		multipartBody,
+		multipartPatternBody,
+		binaryBase64Body,
+		binaryHexBody,
+		gzipBody,
+		deniedContentType,
+		notAllowedContentType,
+	}
+
+	for _, test := range testCases {
+		lt := NewTransportLog(http.DefaultTransport, test.options...).(*logTransport)
+		value, ok := lt.formatBody(test.header, test.body, false)
+
+		require.Equal(t, !test.omitted, ok, test.name)
+		if !test.omitted {
+			require.Equal(t, test.expected, flattenValue(value), test.name)
+		}
+	}
+}
+
+// flattenValue turns group values into maps so they compare easily.
+func flattenValue(value slog.Value) any {
+	if value.Kind() != slog.KindGroup {
+		return value.Any()
+	}
+
+	ans := map[string]slog.Value{}
+	for _, a := range value.Group() {
+		flattenAttr(ans, "", a)
+	}
+
+	flat := map[string]any{}
+	for key, v := range ans {
+		flat[key] = v.Any()
+	}
+
+	return flat
+}
+
+var compactJSONBody = &testLogBodyCase{
+	name:     "compactJSONBody",
+	header:   http.Header{"Content-Type": {"application/json"}},
+	body:     []byte("{\n  \"a\": [1, 2],\n  \"password\": \"p\"\n}"),
+	expected: `{"a":[1,2],"password":"[REDACTED]"}`,
+}
+
+var prettyJSONBody = &testLogBodyCase{
+	name:     "prettyJSONBody",
+	header:   http.Header{"Content-Type": {"application/json"}},
+	body:     []byte(`{"a":1}`),
+	options:  []LogOption{LogOptionPrettyJSON(true)},
+	expected: "{\n  \"a\": 1\n}",
+}
+
+var formBody = &testLogBodyCase{
+	name:     "formBody",
+	header:   http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+	body:     []byte("user=a&password=p&tag=x&tag=y"),
+	expected: url.Values{"user": {"a"}, "password": {"[REDACTED]"}, "tag": {"x", "y"}},
+}
+
+var multipartBody = &testLogBodyCase{
+	name:   "multipartBody",
+	header: http.Header{"Content-Type": {"multipart/form-data; boundary=b"}},
+	body: []byte("--b\r\nContent-Disposition: form-data; name=\"title\"\r\n\r\nhello\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"password\"\r\n\r\np\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"a.png\"\r\nContent-Type: image/png\r\n\r\nPNGDATA\r\n" +
+		"--b--\r\n"),
+	expected: []any{
+		map[string]any{"name": "title", "size": int64(5), "value": "hello"},
+		map[string]any{"name": "password", "size": int64(1), "value": "[REDACTED]"},
+		map[string]any{"name": "file", "size": int64(7), "filename": "a.png", "content_type": "image/png"},
+	},
+}
+
+var multipartPatternBody = &testLogBodyCase{
+	name:   "multipartPatternBody",
+	header: http.Header{"Content-Type": {"multipart/form-data; boundary=b"}},
+	body: []byte("--b\r\nContent-Disposition: form-data; name=\"note\"\r\n\r\ncard 4111 1111 1111 1111\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"meta\"\r\nContent-Type: application/json\r\n\r\n{\"token\":\"t\"}\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"Secret\"\r\n\r\ns\r\n" +
+		"--b--\r\n"),
+	options: []LogOption{LogOptionRedactConfig(RedactConfig{
+		BodyKeys:     []string{"token", "secret"},
+		BodyPatterns: []*regexp.Regexp{RedactPatternCardNumber},
+	})},
+	expected: []any{
+		map[string]any{"name": "note", "size": int64(24), "value": "card [REDACTED]"},
+		map[string]any{"name": "meta", "size": int64(13), "value": `{"token":"[REDACTED]"}`, "content_type": "application/json"},
+		map[string]any{"name": "Secret", "size": int64(1), "value": "[REDACTED]"},
+	},
+}
+
+var binaryBase64Body = &testLogBodyCase{
+	name:     "binaryBase64Body",
+	header:   http.Header{"Content-Type": {"application/x-protobuf"}},
+	body:     []byte{0x08, 0x96, 0x01},
+	options:  []LogOption{LogOptionBinaryEncoding(BinaryEncodingBase64)},
+	expected: map[string]any{"content_type": "application/x-protobuf", "base64": "CJYB"},
+}
+
+var binaryHexBody = &testLogBodyCase{
+	name:     "binaryHexBody",
+	header:   http.Header{"Content-Type": {"application/octet-stream"}},
+	body:     []byte{0x08, 0x96, 0x01},
+	options:  []LogOption{LogOptionBinaryEncoding(BinaryEncodingHex)},
+	expected: map[string]any{"content_type": "application/octet-stream", "hex": "089601"},
+}
+
+var gzipBody = &testLogBodyCase{
+	name:     "gzipBody",
+	header:   http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+	body:     gzipBytes(`{"token":"t","ok":true}`),
+	expected: `{"token":"[REDACTED]","ok":true}`,
+}
+
+var deniedContentType = &testLogBodyCase{
+	name:    "deniedContentType",
+	header:  http.Header{"Content-Type": {"image/png"}},
+	body:    []byte("PNG"),
+	options: []LogOption{LogOptionSkipBodyContentTypes([]string{"image/*"})},
+	omitted: true,
+}
+
+var notAllowedContentType = &testLogBodyCase{
+	name:    "notAllowedContentType",
+	header:  http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+	body:    []byte("<html></html>"),
+	options: []LogOption{LogOptionBodyContentTypes([]string{"application/json", "text/plain"})},
+	omitted: true,
+}
+
+func gzipBytes(s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write([]byte(s))
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func TestLogTransport_RequestIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("X-Upstream", "a")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	handler := &testLogHandler{}
+	client := &http.Client{
+		Transport: NewTransportLog(http.DefaultTransport,
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+			LogOptionResHeaders(true),
+			LogOptionFullURL(true),
+			LogOptionConnInfo(true),
+			LogOptionPathTemplates([]string{"/users/{id}"}),
+		),
+	}
+
+	res, err := client.Get(server.URL + "/users/42?token=t&q=go")
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	attrs := handler.attrs(0)
+	require.Equal(t, server.URL+"/users/:id?q=go&token=%5BREDACTED%5D", attrs["url"].String())
+	require.Equal(t, "HTTP/1.1", attrs["response.proto"].String())
+	require.EqualValues(t, 2, attrs["response.content_length"].Int64())
+	require.EqualValues(t, 0, attrs["request.content_length"].Int64())
+	require.Equal(t, []string{"[REDACTED]"}, attrs["response.headers"].Any().(map[string][]string)["Set-Cookie"])
+	require.Equal(t, []string{"a"}, attrs["response.headers"].Any().(map[string][]string)["X-Upstream"])
+	require.Equal(t, "127.0.0.1", attrs["conn.remote_ip"].String())
+	require.Equal(t, strings.TrimPrefix(server.URL, "http://"), attrs["conn.remote_addr"].String())
+	require.NotEmpty(t, attrs["conn.local_addr"].String())
+}
+
+func TestLogTransport_Timing(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	handler := &testLogHandler{}
+	client := &http.Client{
+		Transport: NewTransportLog(server.Client().Transport,
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+			LogOptionTiming(true),
+		),
+	}
+
+	for range 2 {
+		res, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	first, second := handler.attrs(0), handler.attrs(1)
+	require.Positive(t, first["timing.connect"].Duration())
+	require.Positive(t, first["timing.tls"].Duration())
+	require.Positive(t, first["timing.ttfb"].Duration())
+	require.False(t, first["timing.reused"].Bool())
+
+	require.True(t, second["timing.reused"].Bool())
+	require.True(t, second["timing.was_idle"].Bool())
+	require.Zero(t, second["timing.tls"].Duration())
+}
+
+func TestLogTransport_Failure(t *testing.T) {
+	handler := &testLogHandler{}
+	client := &http.Client{
+		Transport: NewTransportLog(&staticRoundTripper{err: context.DeadlineExceeded},
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+		),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, defaultURL, strings.NewReader(`{"password":"p"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Do(req.WithContext(ctx))
+	require.Error(t, err)
+
+	require.Equal(t, []slog.Level{slog.LevelError}, handler.levels())
+	attrs := handler.attrs(0)
+	require.Equal(t, http.MethodPost, attrs["method"].String())
+	require.Equal(t, defaultPath, attrs["url"].String())
+	require.Equal(t, "req-1", attrs["request_id"].String())
+	require.Equal(t, "timeout", attrs["error_class"].String())
+	require.Equal(t, "canceled", attrs["context"].String())
+	require.Contains(t, attrs, "latency")
+	require.Contains(t, attrs, "headers")
+}
+
+type testLogSamplingCase struct {
+	name     string
+	statuses []int
+	options  []LogOption
+	expected int
+}
+
+func TestLogTransport_Sampling(t *testing.T) {
+	testCases := []*testLogSamplingCase{
+		sampleNone,
+		sampleNoneAlwaysErrors,
+		sampleRouteOverride,
+		sampleSlowThreshold,
+	}
+
+	for _, test := range testCases {
+		handler := &testLogHandler{}
+		options := append([]LogOption{
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+		}, test.options...)
+
+		for _, status := range test.statuses {
+			client := &http.Client{
+				Transport: NewTransportLog(&staticRoundTripper{status: status}, options...),
+			}
+
+			res, err := client.Get(defaultURL)
+			require.NoError(t, err, test.name)
+			require.NoError(t, res.Body.Close(), test.name)
+		}
+
+		require.Len(t, handler.levels(), test.expected, test.name)
+	}
+}
+
+var sampleNone = &testLogSamplingCase{
+	name:     "sampleNone",
+	statuses: []int{http.StatusOK, http.StatusInternalServerError},
+	options:  []LogOption{LogOptionSampleRatio(0)},
+	expected: 0,
+}
+
+var sampleNoneAlwaysErrors = &testLogSamplingCase{
+	name:     "sampleNoneAlwaysErrors",
+	statuses: []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError},
+	options:  []LogOption{LogOptionSampleRatio(0), LogOptionAlwaysLogErrors(true)},
+	expected: 1,
+}
+
+var sampleRouteOverride = &testLogSamplingCase{
+	name:     "sampleRouteOverride",
+	statuses: []int{http.StatusOK, http.StatusOK},
+	options: []LogOption{
+		LogOptionSampleRatio(0),
+		LogOptionSampleRoutes([]SampleRoute{{Pattern: CombinePath(defaultMethod, "/v1/*"), Ratio: 1}}),
+	},
+	expected: 2,
+}
+
+var sampleSlowThreshold = &testLogSamplingCase{
+	name:     "sampleSlowThreshold",
+	statuses: []int{http.StatusOK},
+	options:  []LogOption{LogOptionSampleRatio(0), LogOptionSlowThreshold(time.Nanosecond)},
+	expected: 1,
+}
+
+func TestLogTransport_SamplingDeterministic(t *testing.T) {
+	inner, outer := &testLogHandler{}, &testLogHandler{}
+	options := []LogOption{LogOptionMatcherConfig(logAllMatcherConfig), LogOptionSampleRatio(0.5)}
+	client := &http.Client{
+		Transport: NewTransportLog(
+			NewTransportLog(&staticRoundTripper{status: http.StatusOK}, append(options, LogOptionLogger(slog.New(inner)))...),
+			append(options, LogOptionLogger(slog.New(outer)))...,
+		),
+	}
+
+	for range 200 {
+		res, err := client.Get(defaultURL)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	require.Equal(t, len(outer.levels()), len(inner.levels()))
+	require.InDelta(t, 100, len(outer.levels()), 40)
+	for idx := range outer.levels() {
+		require.Equal(t, outer.attrs(idx)["request_id"], inner.attrs(idx)["request_id"])
+	}
+}
+
+func TestLogRateLimiter_Allow(t *testing.T) {
+	limiter := &logRateLimiter{limit: 2}
+	now := time.Unix(100, 0)
+
+	var allowed []bool
+	for range 5 {
+		ok, suppressed := limiter.allow(now)
+		require.Zero(t, suppressed)
+		allowed = append(allowed, ok)
+	}
+	require.Equal(t, []bool{true, true, false, false, false}, allowed)
+
+	ok, suppressed := limiter.allow(now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, 3, suppressed)
+
+	ok, suppressed = limiter.allow(now.Add(time.Second))
+	require.True(t, ok)
+	require.Zero(t, suppressed)
+}
+
+func TestLogTransport_MaxPerSecondFlush(t *testing.T) {
+	handler := &testLogHandler{}
+	client := &http.Client{
+		Transport: NewTransportLog(&staticRoundTripper{status: http.StatusOK},
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+			LogOptionMaxPerSecond(1),
+		),
+	}
+
+	for range 3 {
+		res, err := client.Get(defaultURL)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	// The requests may straddle two seconds, entries and suppressed ones still add up to 3.
+	require.Eventually(t, func() bool {
+		levels := handler.levels()
+		return levels[len(levels)-1] == slog.LevelWarn
+	}, 3*time.Second, 10*time.Millisecond, "the summary is flushed without further traffic")
+
+	levels := handler.levels()
+	last := len(levels) - 1
+	require.Equal(t, 3, last+int(handler.attrs(last)["suppressed"].Int64()))
+}
+
+type sequenceRoundTripper struct {
+	statuses []int
+	calls    int
+}
+
+func (s *sequenceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := s.statuses[min(s.calls, len(s.statuses)-1)]
+	s.calls++
+
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func TestLogTransport_RetryCorrelation(t *testing.T) {
+	inner, outer := &testLogHandler{}, &testLogHandler{}
+	statuses := []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}
+	client := &http.Client{
+		Transport: NewTransportLog(
+			NewTransportRetry(
+				NewTransportLog(&sequenceRoundTripper{statuses: statuses},
+					LogOptionLogger(slog.New(inner)),
+					LogOptionMatcherConfig(logAllMatcherConfig),
+				),
+				RetryOptionMaxTries(3),
+			),
+			LogOptionLogger(slog.New(outer)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+		),
+	}
+
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	require.Len(t, outer.levels(), 1)
+	outerAttrs := outer.attrs(0)
+	require.EqualValues(t, 3, outerAttrs["attempts"].Int64())
+	require.NotEmpty(t, outerAttrs["request_id"].String())
+
+	require.Len(t, inner.levels(), 3)
+	for idx := range 3 {
+		attrs := inner.attrs(idx)
+		require.EqualValues(t, idx+1, attrs["attempt"].Int64())
+		require.EqualValues(t, 4, attrs["max_attempts"].Int64())
+		require.Equal(t, outerAttrs["request_id"].String(), attrs["request_id"].String())
+		require.NotContains(t, attrs, "attempts")
+	}
+}
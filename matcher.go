@@ -1,14 +1,33 @@
 package transport
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"slices"
+	"strings"
+	"time"
 )
 
+// MatcherConfig selects the requests a middleware acts on. Path lists hold patterns of the form
+// [METHOD|]path, see NewMatcher; blacklisted requests are never matched.
+//
+// The host, scheme, header and query lists narrow the selection further. An empty white list places
+// no restriction, otherwise the request has to match one of its entries; a request matching any
+// black list entry is never matched.
 type MatcherConfig struct {
-	OnStatus       []int // Zero for all status
+	OnStatus       []int    // Zero for all status
+	OnStatusExpr   []string // 503, 5xx, 500-599 or * to add status codes, !501 or !4xx to remove them
 	WhiteListPaths []string
 	BlackListPaths []string
+
+	WhiteListHosts   []string // host[:port], *.example.com for any subdomain
+	BlackListHosts   []string
+	Schemes          []string // Empty for any scheme
+	WhiteListHeaders []string // Name when present, Name=value when one of its values equals value
+	BlackListHeaders []string
+	WhiteListQuery   []string // name when present, name=value when one of its values equals value
+	BlackListQuery   []string
 }
 
 var DefaultMatcherConfig = MatcherConfig{
@@ -22,40 +41,216 @@ type Matcher interface {
 	MatchPath(req *http.Request) bool
 }
 
+// Outcome is how a request ended: with a response, or with an error and no status code.
+type Outcome struct {
+	StatusCode int
+	Err        error
+	ErrorClass ErrorClass    // ClassifyError(Err)
+	Latency    time.Duration // Until the response headers or the error arrived
+}
+
+func newOutcome(res *http.Response, err error, latency time.Duration) Outcome {
+	outcome := Outcome{Err: err, ErrorClass: ClassifyError(err), Latency: latency}
+	if res != nil {
+		outcome.StatusCode = res.StatusCode
+	}
+
+	return outcome
+}
+
+// OutcomeMatcher is implemented by matchers deciding on the whole outcome of a request rather than
+// its status code alone, such as the combinators. See MatchOutcome.
+type OutcomeMatcher interface {
+	MatchOutcome(req *http.Request, outcome Outcome) bool
+}
+
+// MatchOutcome reports whether m selects req ending with outcome. Matchers not implementing
+// OutcomeMatcher decide on responses with Match, and on errors with ErrorMatcher when they
+// implement it; otherwise every error matches.
+func MatchOutcome(m Matcher, req *http.Request, outcome Outcome) bool {
+	switch om := m.(type) {
+	case OutcomeMatcher:
+		return om.MatchOutcome(req, outcome)
+	case ErrorMatcher:
+		if outcome.Err != nil {
+			return om.MatchError(req, outcome.Err)
+		}
+	}
+
+	if outcome.Err != nil {
+		return true
+	}
+
+	return m.Match(req, outcome.StatusCode)
+}
+
+// configMatcher is a MatcherConfig with its patterns compiled.
+type configMatcher struct {
+	onStatus  statusSet
+	whiteList *pathSet
+	blackList *pathSet
+
+	whiteHosts   []hostPattern
+	blackHosts   []hostPattern
+	schemes      []string
+	whiteHeaders []valuePredicate
+	blackHeaders []valuePredicate
+	whiteQuery   []valuePredicate
+	blackQuery   []valuePredicate
+}
+
+// NewMatcher compiles cfg once for matching many requests. Path patterns may be
+//   - * for every request,
+//   - METHOD|/exact/path, with * as the method matching any method, or the path alone,
+//   - globs: /users/*/orders matches one segment, /files/** any number of them,
+//   - route templates: GET|/users/{id}/orders,
+//   - regular expressions: GET|re:^/users/\d+$.
+//
+// A trailing /* matches the path and everything below it. Invalid patterns match nothing, check
+// them with MatcherConfig.Validate.
+//
+// Segment patterns are merged into a trie, so MatchPath does not allocate and barely slows down
+// as patterns are added; regular expressions are still tried one by one.
 func NewMatcher(cfg MatcherConfig) Matcher {
-	return &cfg
+	m := &configMatcher{
+		onStatus:     compileStatusSet(cfg.OnStatus, cfg.OnStatusExpr),
+		whiteList:    newPathSet(compileAll(cfg.WhiteListPaths, compilePathPattern)),
+		blackList:    newPathSet(compileAll(cfg.BlackListPaths, compilePathPattern)),
+		whiteHosts:   compileAll(cfg.WhiteListHosts, compileHostPattern),
+		blackHosts:   compileAll(cfg.BlackListHosts, compileHostPattern),
+		whiteHeaders: compileAll(cfg.WhiteListHeaders, compileHeaderPredicate),
+		blackHeaders: compileAll(cfg.BlackListHeaders, compileHeaderPredicate),
+		whiteQuery:   compileAll(cfg.WhiteListQuery, compileQueryPredicate),
+		blackQuery:   compileAll(cfg.BlackListQuery, compileQueryPredicate),
+	}
+
+	for _, scheme := range cfg.Schemes {
+		m.schemes = append(m.schemes, strings.ToLower(scheme))
+	}
+
+	return m
 }
 
+func compileAll[T any](patterns []string, compile func(string) T) []T {
+	compiled := make([]T, 0, len(patterns))
+	for _, pattern := range patterns {
+		compiled = append(compiled, compile(pattern))
+	}
+
+	return compiled
+}
+
+func compileHeaderPredicate(predicate string) valuePredicate {
+	return compileValuePredicate(predicate, true)
+}
+
+func compileQueryPredicate(predicate string) valuePredicate {
+	return compileValuePredicate(predicate, false)
+}
+
+// MatcherOrConfig returns m, or a matcher compiled from cfg when m is nil, the way middlewares combine
+// their matcher and MatcherConfig options. Patterns of cfg that cannot be compiled are reported to
+// logger as a warning naming middleware: they match nothing, which silently disables a black list entry.
+func MatcherOrConfig(m Matcher, cfg MatcherConfig, logger *slog.Logger, middleware string) Matcher {
+	if m != nil {
+		return m
+	}
+
+	if err := cfg.Validate(); err != nil {
+		logger.Warn("Invalid matcher patterns never match",
+			slog.String("middleware", middleware), slog.String("error", err.Error()))
+	}
+
+	return NewMatcher(cfg)
+}
+
+// Validate reports the status expressions and the path, host, header and query patterns that
+// cannot be compiled.
+func (m MatcherConfig) Validate() error {
+	errs := compileStatusSet(m.OnStatus, m.OnStatusExpr).errs
+	for _, pattern := range slices.Concat(m.WhiteListPaths, m.BlackListPaths) {
+		errs = append(errs, compilePathPattern(pattern).err)
+	}
+
+	for _, pattern := range slices.Concat(m.WhiteListHosts, m.BlackListHosts) {
+		errs = append(errs, compileHostPattern(pattern).err)
+	}
+
+	for _, predicate := range slices.Concat(m.WhiteListHeaders, m.BlackListHeaders, m.WhiteListQuery, m.BlackListQuery) {
+		errs = append(errs, compileValuePredicate(predicate, false).err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Match compiles m on every call, use NewMatcher to match many requests.
 func (m *MatcherConfig) Match(req *http.Request, statusCode int) bool {
-	if slices.Contains(m.OnStatus, NumberZero) {
-		return true
+	return NewMatcher(*m).Match(req, statusCode)
+}
+
+// MatchPath compiles m on every call, use NewMatcher to match many requests.
+func (m *MatcherConfig) MatchPath(req *http.Request) bool {
+	return NewMatcher(*m).MatchPath(req)
+}
+
+func (m *configMatcher) Match(req *http.Request, statusCode int) bool {
+	return m.onStatus.match(statusCode) && m.MatchPath(req)
+}
+
+func (m *configMatcher) MatchPath(req *http.Request) bool {
+	if !m.matchRequest(req) {
+		return false
 	}
 
-	if !slices.Contains(m.OnStatus, statusCode) {
+	if m.blackList.match(req.Method, req.URL.Path) {
 		return false
 	}
 
-	return m.MatchPath(req)
+	return m.whiteList.match(req.Method, req.URL.Path)
 }
 
-func (m *MatcherConfig) MatchPath(req *http.Request) bool {
-	if slices.Contains(m.BlackListPaths, ConsCharStar) {
+// matchRequest applies the scheme, host, header and query lists.
+func (m *configMatcher) matchRequest(req *http.Request) bool {
+	if len(m.schemes) > 0 && !slices.Contains(m.schemes, strings.ToLower(req.URL.Scheme)) {
 		return false
 	}
 
-	combinedPath := CombinePath(req.Method, req.URL.Path)
-	for _, path := range m.BlackListPaths {
-		if MatchesPath(path, combinedPath) {
+	if len(m.whiteHosts) > 0 || len(m.blackHosts) > 0 {
+		host, port := requestHost(req)
+		if !matchLists(m.whiteHosts, m.blackHosts, func(p *hostPattern) bool { return p.match(host, port) }) {
+			return false
+		}
+	}
+
+	if !matchLists(m.whiteHeaders, m.blackHeaders, func(p *valuePredicate) bool { return p.match(req.Header) }) {
+		return false
+	}
+
+	if len(m.whiteQuery) > 0 || len(m.blackQuery) > 0 {
+		query := req.URL.Query()
+		if !matchLists(m.whiteQuery, m.blackQuery, func(p *valuePredicate) bool { return p.match(query) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchLists reports false when match holds for a black list entry, or when the white list is not
+// empty and match holds for none of its entries.
+func matchLists[T any](whiteList, blackList []T, match func(*T) bool) bool {
+	for idx := range blackList {
+		if match(&blackList[idx]) {
 			return false
 		}
 	}
 
-	if slices.Contains(m.WhiteListPaths, ConsCharStar) {
+	if len(whiteList) == 0 {
 		return true
 	}
 
-	for _, path := range m.WhiteListPaths {
-		if MatchesPath(path, combinedPath) {
+	for idx := range whiteList {
+		if match(&whiteList[idx]) {
 			return true
 		}
 	}
@@ -0,0 +1,250 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrorMatcher is implemented by matchers that also decide on requests failing without a response.
+// The retry transport only retries, and the log and circuit breaker transports only log and count,
+// the errors it matches; errors are always matched by matchers implementing neither this nor
+// OutcomeMatcher.
+type ErrorMatcher interface {
+	MatchError(req *http.Request, err error) bool
+}
+
+// matchState is the outcome of a predicate, which may not be known before the response is.
+type matchState uint8
+
+const (
+	matchFalse matchState = iota
+	matchTrue
+	matchUnknown
+)
+
+func matchStateOf(matched bool) matchState {
+	if matched {
+		return matchTrue
+	}
+
+	return matchFalse
+}
+
+type matchInput struct {
+	req     *http.Request
+	outcome *Outcome // Nil in MatchPath, before sending the request
+}
+
+// exprMatcher is a predicate built by the combinators. Before the response is known, predicates on
+// the outcome are undecided, so MatchPath only rejects requests no outcome can make match.
+type exprMatcher struct {
+	eval func(in *matchInput) matchState
+}
+
+func (m *exprMatcher) Match(req *http.Request, statusCode int) bool {
+	return m.MatchOutcome(req, Outcome{StatusCode: statusCode})
+}
+
+func (m *exprMatcher) MatchPath(req *http.Request) bool {
+	return m.eval(&matchInput{req: req}) != matchFalse
+}
+
+func (m *exprMatcher) MatchError(req *http.Request, err error) bool {
+	return m.MatchOutcome(req, Outcome{Err: err, ErrorClass: ClassifyError(err)})
+}
+
+func (m *exprMatcher) MatchOutcome(req *http.Request, outcome Outcome) bool {
+	return m.eval(&matchInput{req: req, outcome: &outcome}) == matchTrue
+}
+
+// evalMatcher evaluates any Matcher as a predicate. MatchPath of other matchers only decides when it
+// rejects the request, and their errors are matched by the request alone unless they implement
+// OutcomeMatcher or ErrorMatcher.
+func evalMatcher(m Matcher, in *matchInput) matchState {
+	if expr, ok := m.(*exprMatcher); ok {
+		return expr.eval(in)
+	}
+
+	if in.outcome == nil {
+		if !m.MatchPath(in.req) {
+			return matchFalse
+		}
+
+		return matchUnknown
+	}
+
+	_, decidesErrors := m.(ErrorMatcher)
+	if _, ok := m.(OutcomeMatcher); !ok && !decidesErrors && in.outcome.Err != nil {
+		return matchStateOf(m.MatchPath(in.req))
+	}
+
+	return matchStateOf(MatchOutcome(m, in.req, *in.outcome))
+}
+
+// requestPredicate matches on the request alone, whatever its outcome.
+func requestPredicate(match func(req *http.Request) bool) Matcher {
+	return &exprMatcher{eval: func(in *matchInput) matchState {
+		return matchStateOf(match(in.req))
+	}}
+}
+
+// outcomePredicate matches on the outcome alone, it is undecided before the request is sent.
+func outcomePredicate(match func(outcome *Outcome) bool) Matcher {
+	return &exprMatcher{eval: func(in *matchInput) matchState {
+		if in.outcome == nil {
+			return matchUnknown
+		}
+
+		return matchStateOf(match(in.outcome))
+	}}
+}
+
+// MatcherFunc adapts a function selecting requests to a Matcher, whatever their outcome.
+// Combine it with StatusIn, ErrorIs or the other outcome predicates to select responses.
+type MatcherFunc func(req *http.Request) bool
+
+func (f MatcherFunc) Match(req *http.Request, _ int) bool {
+	return f(req)
+}
+
+func (f MatcherFunc) MatchPath(req *http.Request) bool {
+	return f(req)
+}
+
+func (f MatcherFunc) MatchError(req *http.Request, _ error) bool {
+	return f(req)
+}
+
+func (f MatcherFunc) MatchOutcome(req *http.Request, _ Outcome) bool {
+	return f(req)
+}
+
+// And matches when all matchers do, And() matches everything.
+func And(matchers ...Matcher) Matcher {
+	return &exprMatcher{eval: func(in *matchInput) matchState {
+		state := matchTrue
+		for _, m := range matchers {
+			switch evalMatcher(m, in) {
+			case matchFalse:
+				return matchFalse
+			case matchUnknown:
+				state = matchUnknown
+			}
+		}
+
+		return state
+	}}
+}
+
+// Or matches when any of matchers does, Or() matches nothing.
+func Or(matchers ...Matcher) Matcher {
+	return &exprMatcher{eval: func(in *matchInput) matchState {
+		state := matchFalse
+		for _, m := range matchers {
+			switch evalMatcher(m, in) {
+			case matchTrue:
+				return matchTrue
+			case matchUnknown:
+				state = matchUnknown
+			}
+		}
+
+		return state
+	}}
+}
+
+// Not matches when m does not.
+func Not(m Matcher) Matcher {
+	return &exprMatcher{eval: func(in *matchInput) matchState {
+		switch state := evalMatcher(m, in); state {
+		case matchTrue:
+			return matchFalse
+		case matchFalse:
+			return matchTrue
+		default:
+			return state
+		}
+	}}
+}
+
+// MethodIs matches requests with one of methods, compared case-insensitively.
+func MethodIs(methods ...string) Matcher {
+	return requestPredicate(func(req *http.Request) bool {
+		return slices.ContainsFunc(methods, func(method string) bool {
+			return strings.EqualFold(method, req.Method)
+		})
+	})
+}
+
+// HostIs matches requests to one of patterns, host[:port] with *.example.com for any subdomain, like
+// MatcherConfig.WhiteListHosts.
+func HostIs(patterns ...string) Matcher {
+	compiled := compileAll(patterns, compileHostPattern)
+	return requestPredicate(func(req *http.Request) bool {
+		host, port := requestHost(req)
+		return slices.ContainsFunc(compiled, func(p hostPattern) bool {
+			return p.match(host, port)
+		})
+	})
+}
+
+// PathMatches matches requests matching one of patterns, [METHOD|]path as described on NewMatcher.
+func PathMatches(patterns ...string) Matcher {
+	set := newPathSet(compileAll(patterns, compilePathPattern))
+	return requestPredicate(func(req *http.Request) bool {
+		return set.match(req.Method, req.URL.Path)
+	})
+}
+
+// HeaderEquals matches requests with a name header equal to value.
+func HeaderEquals(name, value string) Matcher {
+	name = http.CanonicalHeaderKey(name)
+	return requestPredicate(func(req *http.Request) bool {
+		return slices.Contains(req.Header[name], value)
+	})
+}
+
+// StatusIn matches responses with a status code selected by exprs, like MatcherConfig.OnStatusExpr:
+// 503, 5xx, 500-599, * and negations such as !501. Failed requests have no status and never match.
+func StatusIn(exprs ...string) Matcher {
+	set := compileStatusSet(nil, exprs)
+	return outcomePredicate(func(outcome *Outcome) bool {
+		return outcome.Err == nil && set.match(outcome.StatusCode)
+	})
+}
+
+// ErrorIs matches failed requests whose error wraps target, any failed request when target is nil.
+// Responses never match.
+func ErrorIs(target error) Matcher {
+	return outcomePredicate(func(outcome *Outcome) bool {
+		return outcome.Err != nil && (target == nil || errors.Is(outcome.Err, target))
+	})
+}
+
+// ErrorClassIs matches failed requests whose error is of one of classes, see ClassifyError.
+// Responses never match.
+func ErrorClassIs(classes ...ErrorClass) Matcher {
+	return outcomePredicate(func(outcome *Outcome) bool {
+		if outcome.Err == nil {
+			return false
+		}
+
+		class := outcome.ErrorClass
+		if class == ErrorClassNone {
+			class = ClassifyError(outcome.Err)
+		}
+
+		return slices.Contains(classes, class)
+	})
+}
+
+// LatencyAtLeast matches outcomes that took at least d. Outcomes matched through Match carry no
+// latency and only match a zero d.
+func LatencyAtLeast(d time.Duration) Matcher {
+	return outcomePredicate(func(outcome *Outcome) bool {
+		return outcome.Latency >= d
+	})
+}
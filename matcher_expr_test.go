@@ -0,0 +1,220 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRoundTripper counts the requests reaching tp.
+type countingRoundTripper struct {
+	tp    http.RoundTripper
+	calls int
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls++
+	return c.tp.RoundTrip(req)
+}
+
+func newMatcherRequest(t *testing.T, method, url string) *http.Request {
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+
+	return req
+}
+
+func TestMatcher_Combinators(t *testing.T) {
+	get := newMatcherRequest(t, http.MethodGet, "https://api.example.com/users/42")
+	post := newMatcherRequest(t, http.MethodPost, "http://payments.internal:8080/charge")
+	post.Header.Set("X-Tenant", "acme")
+
+	retryable := And(MethodIs(http.MethodGet), StatusIn("5xx", "!501"))
+	require.True(t, retryable.MatchPath(get))
+	require.False(t, retryable.MatchPath(post))
+	require.True(t, retryable.Match(get, http.StatusBadGateway))
+	require.False(t, retryable.Match(get, http.StatusNotImplemented))
+	require.False(t, retryable.Match(post, http.StatusBadGateway))
+
+	notOK := Not(StatusIn("2xx"))
+	require.True(t, notOK.MatchPath(get), "undecided before the response")
+	require.True(t, notOK.Match(get, http.StatusNotFound))
+	require.False(t, notOK.Match(get, http.StatusOK))
+
+	require.True(t, HostIs("*.example.com").MatchPath(get))
+	require.True(t, HostIs("payments.internal:8080").MatchPath(post))
+	require.False(t, HostIs("payments.internal:9090").MatchPath(post))
+	require.True(t, PathMatches("GET|/users/{id}").Match(get, http.StatusOK))
+	require.False(t, PathMatches("GET|/users/{id}").MatchPath(post))
+	require.True(t, HeaderEquals("x-tenant", "acme").MatchPath(post))
+	require.False(t, HeaderEquals("x-tenant", "other").MatchPath(post))
+
+	secure := MatcherFunc(func(req *http.Request) bool { return req.URL.Scheme == "https" })
+	require.True(t, Or(secure, HostIs("*.internal")).MatchPath(post))
+	require.False(t, And(secure, Not(HostIs("*.internal"))).MatchPath(post))
+
+	require.True(t, And().MatchPath(get))
+	require.False(t, Or().MatchPath(get))
+}
+
+func TestMatcher_CombinatorsWithConfig(t *testing.T) {
+	cfg := NewMatcher(MatcherConfig{OnStatus: []int{http.StatusServiceUnavailable}, WhiteListPaths: []string{"/users/*"}})
+	m := And(cfg, Not(MethodIs(http.MethodDelete)))
+
+	req := newMatcherRequest(t, http.MethodGet, "http://example.com/users/1")
+	require.True(t, m.MatchPath(req))
+	require.True(t, m.Match(req, http.StatusServiceUnavailable))
+	require.False(t, m.Match(req, http.StatusBadGateway))
+	require.False(t, m.MatchPath(newMatcherRequest(t, http.MethodGet, "http://example.com/orders")))
+	require.False(t, m.MatchPath(newMatcherRequest(t, http.MethodDelete, "http://example.com/users/1")))
+}
+
+func TestMatcher_ErrorIs(t *testing.T) {
+	req := newMatcherRequest(t, http.MethodGet, defaultURL)
+	errBoom := errors.New("boom")
+
+	m := Or(StatusIn("503"), ErrorIs(errBoom)).(ErrorMatcher)
+	require.True(t, m.MatchError(req, errBoom))
+	require.False(t, m.MatchError(req, errors.New("other")))
+	require.True(t, ErrorIs(nil).(ErrorMatcher).MatchError(req, errBoom))
+	require.False(t, ErrorIs(errBoom).Match(req, http.StatusServiceUnavailable))
+	require.True(t, MatcherFunc(func(*http.Request) bool { return true }).MatchError(req, errBoom))
+}
+
+func TestRetryTransport_OptionMatcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	testCases := []struct {
+		name          string
+		matcher       Matcher
+		expectedCalls int
+	}{
+		{name: "matched error", matcher: Or(StatusIn("503"), ErrorIs(errBoom)), expectedCalls: 3},
+		{name: "unmatched error", matcher: StatusIn("503"), expectedCalls: 1},
+		{name: "unmatched request", matcher: MethodIs(http.MethodPost), expectedCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &countingRoundTripper{tp: NewTransportFault(nil, FaultOptionError(1, errBoom))}
+			client := &http.Client{Transport: NewTransportRetry(inner, RetryOptionMaxTries(2), RetryOptionMatcher(tc.matcher))}
+
+			_, err := client.Get(defaultURL)
+			require.ErrorIs(t, err, errBoom)
+			require.Equal(t, tc.expectedCalls, inner.calls)
+		})
+	}
+}
+
+func TestCircuitBreakerTransport_OptionMatcher(t *testing.T) {
+	errBoom := errors.New("boom")
+	inner := &countingRoundTripper{tp: NewTransportFault(nil, FaultOptionError(1, errBoom))}
+	client := &http.Client{
+		Transport: NewCircuitBreakerTransport(inner,
+			CircuitBreakerOptionLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			CircuitBreakerOptionMatcher(StatusIn("5xx")),
+			CircuitBreakerOptionBreakerConfig(gobreaker.Settings{
+				ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+				Timeout:     time.Minute,
+			}),
+		),
+	}
+
+	for range 4 {
+		_, err := client.Get(defaultURL)
+		require.ErrorIs(t, err, errBoom)
+	}
+
+	require.Equal(t, 4, inner.calls, "errors StatusIn does not match never trip the breaker")
+}
+
+// classErrorMatcher only implements ErrorMatcher on top of a MatcherConfig.
+type classErrorMatcher struct {
+	Matcher
+	class ErrorClass
+}
+
+func (m classErrorMatcher) MatchError(_ *http.Request, err error) bool {
+	return ClassifyError(err) == m.class
+}
+
+func TestMatchOutcome(t *testing.T) {
+	req := newMatcherRequest(t, http.MethodGet, defaultURL)
+	refused := FaultOptionError(1, nil)
+	_, errRefused := NewTransportFault(nil, refused).RoundTrip(req)
+	require.Equal(t, ErrorClassRefused, ClassifyError(errRefused))
+
+	cfg := NewMatcher(DefaultMatcherConfig)
+	require.True(t, MatchOutcome(cfg, req, Outcome{StatusCode: http.StatusServiceUnavailable}))
+	require.False(t, MatchOutcome(cfg, req, Outcome{StatusCode: http.StatusOK}))
+	require.True(t, MatchOutcome(cfg, req, newOutcome(nil, errRefused, 0)), "errors always match a MatcherConfig")
+
+	custom := classErrorMatcher{Matcher: cfg, class: ErrorClassTimeout}
+	require.False(t, MatchOutcome(custom, req, newOutcome(nil, errRefused, 0)))
+	require.True(t, MatchOutcome(custom, req, Outcome{StatusCode: http.StatusBadGateway}))
+
+	m := Or(StatusIn("5xx"), ErrorClassIs(ErrorClassTimeout, ErrorClassReset), And(StatusIn("2xx"), LatencyAtLeast(time.Second)))
+	require.True(t, MatchOutcome(m, req, Outcome{StatusCode: http.StatusBadGateway}))
+	require.True(t, MatchOutcome(m, req, Outcome{StatusCode: http.StatusOK, Latency: 2 * time.Second}))
+	require.False(t, MatchOutcome(m, req, Outcome{StatusCode: http.StatusOK, Latency: time.Millisecond}))
+	require.True(t, MatchOutcome(m, req, newOutcome(nil, faultTimeoutError{}, time.Second)))
+	require.False(t, MatchOutcome(m, req, newOutcome(nil, errRefused, 0)))
+	require.True(t, m.Match(req, http.StatusInternalServerError), "Match is kept for status codes")
+
+	require.True(t, And(custom, ErrorIs(nil)).(OutcomeMatcher).MatchOutcome(req, newOutcome(nil, faultTimeoutError{}, 0)))
+	require.False(t, And(custom, ErrorIs(nil)).(OutcomeMatcher).MatchOutcome(req, newOutcome(nil, errRefused, 0)))
+	require.True(t, ErrorClassIs(ErrorClassRefused).(OutcomeMatcher).MatchOutcome(req, Outcome{Err: errRefused}), "the class is derived when missing")
+}
+
+func TestCircuitBreakerTransport_ErrorClass(t *testing.T) {
+	inner := &countingRoundTripper{tp: NewTransportFault(nil, FaultOptionTimeout(1, 0))}
+	client := &http.Client{
+		Transport: NewCircuitBreakerTransport(inner,
+			CircuitBreakerOptionLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			CircuitBreakerOptionMatcher(Or(StatusIn("5xx"), ErrorClassIs(ErrorClassTimeout))),
+			CircuitBreakerOptionBreakerConfig(gobreaker.Settings{
+				ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+				Timeout:     time.Minute,
+			}),
+		),
+	}
+
+	for range 4 {
+		_, err := client.Get(defaultURL)
+		require.Error(t, err)
+	}
+
+	require.Equal(t, 2, inner.calls, "timeouts trip the breaker")
+}
+
+func TestLogTransport_OutcomeMatcher(t *testing.T) {
+	handler := &testLogHandler{}
+	inner := NewTransportFault(&staticRoundTripper{status: http.StatusOK},
+		FaultOptionMatcherConfig(MatcherConfig{WhiteListPaths: []string{"/timeout", "/refused"}}),
+		FaultOptionTimeout(1, 0),
+	)
+	refused := NewTransportFault(inner,
+		FaultOptionMatcherConfig(MatcherConfig{WhiteListPaths: []string{"/refused"}}),
+		FaultOptionError(1, nil),
+	)
+	client := &http.Client{
+		Transport: NewTransportLog(refused,
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcher(Or(StatusIn("5xx"), ErrorClassIs(ErrorClassTimeout))),
+		),
+	}
+
+	for _, path := range []string{"/timeout", "/refused", "/ok"} {
+		res, err := client.Get("http://example.com" + path)
+		if err == nil {
+			require.NoError(t, res.Body.Close())
+		}
+	}
+
+	require.Equal(t, []slog.Level{slog.LevelError}, handler.levels(), "only the timeout is logged")
+}
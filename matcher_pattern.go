@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexPathPrefix marks the path part of a pattern as a regular expression, e.g. GET|re:^/users/\d+$.
+const RegexPathPrefix = "re:"
+
+// pathPattern is a compiled WhiteListPaths or BlackListPaths entry of the form [METHOD|]path.
+//
+// The method is optional, * or omitted for any method. The path is either a regular expression
+// after RegexPathPrefix, matched against the request path, or a pathTemplate. A lone * matches
+// every request.
+type pathPattern struct {
+	raw      string
+	method   string
+	anyPath  bool
+	regex    *regexp.Regexp
+	template *pathTemplate // Nil for regular expressions and any-path patterns
+	err      error
+}
+
+func compilePathPattern(pattern string) pathPattern {
+	p := pathPattern{raw: pattern}
+	if pattern == ConsCharStar {
+		p.anyPath = true
+		return p
+	}
+
+	// A bare regular expression has no method and may contain | itself.
+	method, pathPart, ok := strings.Cut(pattern, ConsCharVerticalBar)
+	if !ok || strings.HasPrefix(pattern, RegexPathPrefix) {
+		method, pathPart = "", pattern
+	}
+
+	if method != ConsCharStar {
+		p.method = method
+	}
+
+	switch {
+	case pathPart == ConsCharStar:
+		p.anyPath = true
+	case strings.HasPrefix(pathPart, RegexPathPrefix):
+		p.regex, p.err = regexp.Compile(strings.TrimPrefix(pathPart, RegexPathPrefix))
+	default:
+		template := parsePathTemplate(pathPart)
+		p.template, p.err = &template, template.err
+	}
+
+	if p.err != nil {
+		p.err = fmt.Errorf("transport: invalid path pattern %q: %w", pattern, p.err)
+	}
+
+	return p
+}
+
+// match reports whether a request with method and path matches. Invalid patterns match nothing.
+func (p *pathPattern) match(method, reqPath string) bool {
+	if p.err != nil || (p.method != "" && p.method != method) {
+		return false
+	}
+
+	switch {
+	case p.anyPath:
+		return true
+	case p.regex != nil:
+		return p.regex.MatchString(reqPath)
+	}
+
+	return p.template.match(reqPath)
+}
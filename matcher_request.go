@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// hostPattern is a compiled WhiteListHosts or BlackListHosts entry of the form host[:port].
+//
+// The host matches case-insensitively, *.example.com matches any subdomain of example.com but not
+// example.com itself, and * any host. Without a port any port matches; an explicit port is compared
+// with the port of the request, or the default port of its scheme.
+type hostPattern struct {
+	raw    string
+	host   string
+	suffix string // Set for *.example.com patterns, with its leading dot
+	port   string
+	err    error
+}
+
+func compileHostPattern(pattern string) hostPattern {
+	p := hostPattern{raw: pattern, host: strings.ToLower(pattern)}
+	if host, port, err := net.SplitHostPort(p.host); err == nil {
+		p.host, p.port = host, port
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			p.err = fmt.Errorf("transport: invalid host pattern %q: bad port %q", pattern, port)
+		}
+	}
+
+	switch {
+	case p.host == "":
+		p.err = fmt.Errorf("transport: invalid host pattern %q: empty host", pattern)
+	case strings.HasPrefix(p.host, "*."):
+		p.suffix = p.host[1:]
+	case p.host != ConsCharStar && strings.Contains(p.host, ConsCharStar):
+		p.err = fmt.Errorf("transport: invalid host pattern %q: * is only allowed as the first label", pattern)
+	}
+
+	return p
+}
+
+// match reports whether host and port, both lower case, match. Invalid patterns match nothing.
+func (p *hostPattern) match(host, port string) bool {
+	if p.err != nil || (p.port != "" && p.port != port) {
+		return false
+	}
+
+	switch {
+	case p.host == ConsCharStar:
+		return true
+	case p.suffix != "":
+		return len(host) > len(p.suffix) && strings.HasSuffix(host, p.suffix)
+	}
+
+	return p.host == host
+}
+
+// requestHost returns the lower case host and port of req, the port defaulting to the one of its scheme.
+func requestHost(req *http.Request) (string, string) {
+	hostPort := req.URL.Host
+	if hostPort == "" {
+		hostPort = req.Host
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host, port = hostPort, ""
+	}
+
+	if port == "" {
+		switch strings.ToLower(req.URL.Scheme) {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+
+	return strings.ToLower(strings.Trim(host, "[]")), port
+}
+
+// valuePredicate is a compiled header or query entry of the form name, which holds when the name is
+// present, or name=value, which holds when one of its values equals value.
+type valuePredicate struct {
+	name     string
+	value    string
+	hasValue bool
+	err      error
+}
+
+func compileValuePredicate(predicate string, header bool) valuePredicate {
+	name, value, hasValue := strings.Cut(predicate, "=")
+	p := valuePredicate{name: strings.TrimSpace(name), value: value, hasValue: hasValue}
+	if header {
+		p.name = http.CanonicalHeaderKey(p.name)
+	}
+
+	if p.name == "" {
+		p.err = fmt.Errorf("transport: invalid predicate %q: empty name", predicate)
+	}
+
+	return p
+}
+
+// match reports whether values, looked up by the predicate name, satisfy it. Invalid predicates match nothing.
+func (p *valuePredicate) match(values map[string][]string) bool {
+	if p.err != nil {
+		return false
+	}
+
+	found, ok := values[p.name]
+	if !ok {
+		return false
+	}
+
+	if !p.hasValue {
+		return true
+	}
+
+	for _, value := range found {
+		if value == p.value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusSet is the compiled union of OnStatus and OnStatusExpr.
+type statusSet struct {
+	all      bool
+	include  []StatusRange
+	exclude  []StatusRange
+	errs     []error
+	positive bool // Whether any entry adds status codes, a set of negations alone starts from all of them
+}
+
+func compileStatusSet(codes []int, exprs []string) statusSet {
+	var s statusSet
+	for _, code := range codes {
+		s.positive = true
+		if code == NumberZero {
+			s.all = true
+			continue
+		}
+
+		s.include = append(s.include, StatusRange{From: code, To: code})
+	}
+
+	for _, expr := range exprs {
+		expr = strings.TrimSpace(expr)
+		negated := strings.HasPrefix(expr, "!")
+		body := strings.TrimPrefix(expr, "!")
+
+		if body == ConsCharStar && !negated {
+			s.positive, s.all = true, true
+			continue
+		}
+
+		r, err := parseStatusRange(body)
+		if err != nil {
+			s.errs = append(s.errs, fmt.Errorf("transport: invalid status expression %q: %w", expr, err))
+			continue
+		}
+
+		if negated {
+			s.exclude = append(s.exclude, r)
+		} else {
+			s.positive = true
+			s.include = append(s.include, r)
+		}
+	}
+
+	if !s.positive && len(s.exclude) > 0 {
+		s.all = true
+	}
+
+	return s
+}
+
+// parseStatusRange parses 503, 5xx or 500-599.
+func parseStatusRange(expr string) (StatusRange, error) {
+	if len(expr) == 3 && strings.EqualFold(expr[1:], "xx") && expr[0] >= '1' && expr[0] <= '9' {
+		return StatusClass(int(expr[0] - '0')), nil
+	}
+
+	lowText, highText, isRange := strings.Cut(expr, "-")
+	if !isRange {
+		highText = lowText
+	}
+
+	low, err := strconv.Atoi(lowText)
+	if err != nil {
+		return StatusRange{}, err
+	}
+
+	high, err := strconv.Atoi(highText)
+	if err != nil {
+		return StatusRange{}, err
+	}
+
+	if low > high {
+		return StatusRange{}, errors.New("range is reversed")
+	}
+
+	return StatusRange{From: low, To: high}, nil
+}
+
+func (s *statusSet) match(statusCode int) bool {
+	for _, r := range s.exclude {
+		if r.Contains(statusCode) {
+			return false
+		}
+	}
+
+	if s.all {
+		return true
+	}
+
+	for _, r := range s.include {
+		if r.Contains(statusCode) {
+			return true
+		}
+	}
+
+	return false
+}
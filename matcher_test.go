@@ -1,6 +1,8 @@
 package transport
 
 import (
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"testing"
@@ -26,6 +28,7 @@ func TestMatcher_Cases(t *testing.T) {
 		matchStatusWhileListPath,
 		matchStatusWhileListPathBlackListPath,
 		matchStatusBlackListPathAll,
+		matchAllStatusBlackListPath,
 	}
 
 	for _, test := range testCases {
@@ -120,3 +123,212 @@ var matchStatusBlackListPathAll = &testMatcherCase{
 	expectedResult: false,
 	status:         http.StatusServiceUnavailable,
 }
+
+func TestMatcher_PathPatterns(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		method   string
+		path     string
+		expected bool
+	}{
+		{pattern: "GET|/users", method: http.MethodGet, path: "/users", expected: true},
+		{pattern: "GET|/users", method: http.MethodPost, path: "/users", expected: false},
+		{pattern: "*|/health", method: http.MethodHead, path: "/health", expected: true},
+		{pattern: "/health", method: http.MethodPost, path: "/health", expected: true},
+		{pattern: "GET|/users/*", method: http.MethodGet, path: "/users", expected: true},
+		{pattern: "GET|/users/*", method: http.MethodGet, path: "/users/1/orders", expected: true},
+		{pattern: "GET|/users/*", method: http.MethodGet, path: "/usersettings", expected: false},
+		{pattern: "GET|/users/*/orders", method: http.MethodGet, path: "/users/1/orders", expected: true},
+		{pattern: "GET|/users/*/orders", method: http.MethodGet, path: "/users/1/2/orders", expected: false},
+		{pattern: "GET|/files/**/raw", method: http.MethodGet, path: "/files/raw", expected: true},
+		{pattern: "GET|/files/**/raw", method: http.MethodGet, path: "/files/a/b/raw", expected: true},
+		{pattern: "GET|/files/**/raw", method: http.MethodGet, path: "/files/a/b/raw/x", expected: false},
+		{pattern: "GET|/users/{id}/orders", method: http.MethodGet, path: "/users/42/orders", expected: true},
+		{pattern: "GET|/users/{id}/orders", method: http.MethodGet, path: "/users/42", expected: false},
+		{pattern: "GET|/v*/users", method: http.MethodGet, path: "/v2/users", expected: true},
+		{pattern: `GET|re:^/users/\d+$`, method: http.MethodGet, path: "/users/42", expected: true},
+		{pattern: `GET|re:^/users/\d+$`, method: http.MethodGet, path: "/users/bob", expected: false},
+		{pattern: `re:^/(a|b)$`, method: http.MethodDelete, path: "/b", expected: true},
+		{pattern: `GET|re:(`, method: http.MethodGet, path: "/", expected: false},
+		{pattern: "*", method: http.MethodPut, path: "/anything", expected: true},
+	}
+
+	for _, test := range testCases {
+		matcher := NewMatcher(MatcherConfig{WhiteListPaths: []string{test.pattern}})
+		req := &http.Request{Method: test.method, URL: &url.URL{Path: test.path}}
+		require.Equal(t, test.expected, matcher.MatchPath(req), "%s %s %s", test.pattern, test.method, test.path)
+	}
+}
+
+func TestMatcherConfig_Validate(t *testing.T) {
+	require.NoError(t, DefaultMatcherConfig.Validate())
+	require.Error(t, MatcherConfig{WhiteListPaths: []string{`re:(`}}.Validate())
+	require.Error(t, MatcherConfig{BlackListPaths: []string{`GET|/a/[`}}.Validate())
+}
+
+func TestMatcherConfig_ValidateWarning(t *testing.T) {
+	handler := &testLogHandler{}
+	NewTransportLog(nil, LogOptionLogger(slog.New(handler)),
+		LogOptionMatcherConfig(MatcherConfig{WhiteListPaths: []string{ConsCharStar}, BlackListPaths: []string{"GET|/admin/["}}))
+	require.Equal(t, []slog.Level{slog.LevelWarn}, handler.levels())
+	require.Equal(t, LayerLog, handler.attrs(0)["middleware"].String())
+
+	handler = &testLogHandler{}
+	NewTransportLog(nil, LogOptionLogger(slog.New(handler)), LogOptionMatcherConfig(DefaultMatcherConfig))
+	require.Empty(t, handler.levels(), "valid patterns are not reported")
+}
+
+func TestPathTemplate_NormalizeMatches(t *testing.T) {
+	templates := []string{"/users/{id}", "users/{id}/orders/{orderID}", "/files/**/{name}", "/v*/items", "/a/*", "/", "/a/["}
+	paths := []string{"/", "", "/users/42", "/users/42/", "/users//", "users/42", "/users/42/orders/7", "/files/a/b/raw", "/files/raw", "/v2/items", "/a", "/a/b/c"}
+
+	for _, raw := range templates {
+		template := parsePathTemplate(raw)
+		pattern := compilePathPattern(raw)
+		for _, reqPath := range paths {
+			_, normalized := template.normalize(reqPath)
+			require.Equal(t, pattern.match(http.MethodGet, reqPath), normalized, "%s %s", raw, reqPath)
+			require.Equal(t, template.match(reqPath), normalized, "%s %s", raw, reqPath)
+		}
+	}
+
+	for reqPath, expected := range map[string]string{
+		"/users/42":          "/users/:id",
+		"/files/a/b/raw.txt": "/files/a/b/:name",
+		"/a/b/c":             "/a/b/c",
+		"/users/42/":         "/users/42/",
+		"/":                  "/",
+	} {
+		templates := []pathTemplate{parsePathTemplate("/users/{id}"), parsePathTemplate("/files/**/{name}"), parsePathTemplate("/a/*"), parsePathTemplate("/")}
+		require.Equal(t, expected, normalizeRoute(templates, reqPath), reqPath)
+	}
+}
+
+func TestMatchesPath_Boundary(t *testing.T) {
+	require.True(t, MatchesPath("GET|/users/*", CombinePath(http.MethodGet, "/users/1")))
+	require.False(t, MatchesPath("GET|/users/*", CombinePath(http.MethodGet, "/usersettings")))
+	require.False(t, MatchesPath(ConsCharStar, CombinePath(http.MethodGet, "/users")))
+}
+
+func TestMatcher_RequestPredicates(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   MatcherConfig
+		url      string
+		header   http.Header
+		expected bool
+	}{
+		{name: "exact host", config: MatcherConfig{WhiteListHosts: []string{"payments.internal"}}, url: "http://payments.internal/charge", expected: true},
+		{name: "host case", config: MatcherConfig{WhiteListHosts: []string{"Payments.Internal"}}, url: "http://payments.internal:8080/charge", expected: true},
+		{name: "other host", config: MatcherConfig{WhiteListHosts: []string{"payments.internal"}}, url: "http://users.internal/", expected: false},
+		{name: "wildcard subdomain", config: MatcherConfig{WhiteListHosts: []string{"*.example.com"}}, url: "https://api.eu.example.com/", expected: true},
+		{name: "wildcard apex", config: MatcherConfig{WhiteListHosts: []string{"*.example.com"}}, url: "https://example.com/", expected: false},
+		{name: "port", config: MatcherConfig{WhiteListHosts: []string{"localhost:8080"}}, url: "http://localhost:8080/", expected: true},
+		{name: "other port", config: MatcherConfig{WhiteListHosts: []string{"localhost:8080"}}, url: "http://localhost:9090/", expected: false},
+		{name: "default port", config: MatcherConfig{WhiteListHosts: []string{"example.com:443"}}, url: "https://example.com/", expected: true},
+		{name: "any host on port", config: MatcherConfig{WhiteListHosts: []string{"*:9090"}}, url: "http://localhost:9090/", expected: true},
+		{name: "blacklisted host", config: MatcherConfig{BlackListHosts: []string{"*.internal"}}, url: "http://payments.internal/", expected: false},
+		{name: "scheme", config: MatcherConfig{Schemes: []string{"HTTPS"}}, url: "https://example.com/", expected: true},
+		{name: "other scheme", config: MatcherConfig{Schemes: []string{"https"}}, url: "http://example.com/", expected: false},
+		{name: "header present", config: MatcherConfig{WhiteListHeaders: []string{"x-tenant"}}, url: defaultURL, header: http.Header{"X-Tenant": {"a"}}, expected: true},
+		{name: "header missing", config: MatcherConfig{WhiteListHeaders: []string{"X-Tenant"}}, url: defaultURL, expected: false},
+		{name: "header value", config: MatcherConfig{WhiteListHeaders: []string{"X-Tenant=b"}}, url: defaultURL, header: http.Header{"X-Tenant": {"a", "b"}}, expected: true},
+		{name: "blacklisted header", config: MatcherConfig{BlackListHeaders: []string{"X-Health-Check=1"}}, url: defaultURL, header: http.Header{"X-Health-Check": {"1"}}, expected: false},
+		{name: "other header value", config: MatcherConfig{BlackListHeaders: []string{"X-Health-Check=1"}}, url: defaultURL, header: http.Header{"X-Health-Check": {"0"}}, expected: true},
+		{name: "query present", config: MatcherConfig{WhiteListQuery: []string{"debug"}}, url: "http://example.com/?debug", expected: true},
+		{name: "query value", config: MatcherConfig{WhiteListQuery: []string{"v=2"}}, url: "http://example.com/?v=1", expected: false},
+		{name: "blacklisted query", config: MatcherConfig{BlackListQuery: []string{"dry_run=true"}}, url: "http://example.com/?dry_run=true", expected: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.WhiteListPaths = []string{ConsCharStar}
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			maps.Copy(req.Header, test.header)
+
+			require.Equal(t, test.expected, NewMatcher(test.config).MatchPath(req))
+		})
+	}
+}
+
+func TestMatcherConfig_ValidateRequestPredicates(t *testing.T) {
+	require.Error(t, MatcherConfig{WhiteListHosts: []string{"api.*.com"}}.Validate())
+	require.Error(t, MatcherConfig{BlackListHosts: []string{"localhost:http"}}.Validate())
+	require.Error(t, MatcherConfig{WhiteListHeaders: []string{"=1"}}.Validate())
+	require.NoError(t, MatcherConfig{WhiteListHosts: []string{"*.example.com:8443"}, WhiteListQuery: []string{"v=2"}}.Validate())
+}
+
+var matchAllStatusBlackListPath = &testMatcherCase{
+	name: "matchAllStatusBlackListPath",
+	config: MatcherConfig{
+		OnStatus:       []int{NumberZero},
+		WhiteListPaths: []string{ConsCharStar},
+		BlackListPaths: []string{ConsCharStar},
+	},
+	method:         defaultMethod,
+	url:            defaultURL,
+	status:         http.StatusServiceUnavailable,
+	expectedResult: false,
+}
+
+func TestMatcher_StatusExpressions(t *testing.T) {
+	testCases := []struct {
+		codes    []int
+		exprs    []string
+		matched  []int
+		rejected []int
+	}{
+		{exprs: []string{"5xx"}, matched: []int{500, 501, 599}, rejected: []int{499, 600}},
+		{exprs: []string{"5xx", "!501"}, matched: []int{500, 502}, rejected: []int{501, 404}},
+		{exprs: []string{"500-504"}, matched: []int{500, 504}, rejected: []int{505}},
+		{exprs: []string{"!4xx"}, matched: []int{200, 503}, rejected: []int{400, 429}},
+		{exprs: []string{"*", "!200-299"}, matched: []int{302, 503}, rejected: []int{200, 204}},
+		{codes: []int{429}, exprs: []string{"502-504"}, matched: []int{429, 503}, rejected: []int{500}},
+		{codes: []int{NumberZero}, exprs: []string{"!404"}, matched: []int{200, 500}, rejected: []int{404}},
+		{exprs: []string{"5XX", "!oops"}, matched: []int{503}, rejected: []int{200}},
+	}
+
+	req := &http.Request{Method: defaultMethod, URL: &url.URL{Path: "/"}}
+	for _, test := range testCases {
+		matcher := NewMatcher(MatcherConfig{OnStatus: test.codes, OnStatusExpr: test.exprs, WhiteListPaths: []string{ConsCharStar}})
+		for _, code := range test.matched {
+			require.True(t, matcher.Match(req, code), "%v %v %d", test.codes, test.exprs, code)
+		}
+
+		for _, code := range test.rejected {
+			require.False(t, matcher.Match(req, code), "%v %v %d", test.codes, test.exprs, code)
+		}
+	}
+}
+
+func TestMatcherConfig_ValidateStatus(t *testing.T) {
+	require.NoError(t, MatcherConfig{OnStatusExpr: []string{"5xx", "!501", "400-404", "*"}}.Validate())
+	require.Error(t, MatcherConfig{OnStatusExpr: []string{"5yy"}}.Validate())
+	require.Error(t, MatcherConfig{OnStatusExpr: []string{"599-500"}}.Validate())
+	require.Error(t, MatcherConfig{OnStatusExpr: []string{"!*"}}.Validate())
+}
+
+func TestMatchesPath_LiteralFastPath(t *testing.T) {
+	patterns := []string{
+		"", "/", "/*", "/users", "users", "/users/", "/users/*", "GET|/users", "GET|/users/*", "POST|/",
+		"/a/b/*", "GET|/a//b", "GET|/users/*/orders", "*|/users", `GET|re:^/users$`, "/users/{id}",
+	}
+	paths := []string{"", "/", "/users", "users", "/users/", "/users/1", "/usersettings", "/a/b", "/a/b/c", "/a//b", "/ab"}
+
+	for _, pattern := range patterns {
+		for _, method := range []string{"", http.MethodGet, http.MethodPost} {
+			for _, reqPath := range paths {
+				compiled := compilePathPattern(pattern)
+				matched, ok := matchesLiteralPath(pattern, method, reqPath)
+				if ok {
+					require.Equal(t, compiled.match(method, reqPath), matched, "%q %q %q", pattern, method, reqPath)
+				}
+
+				require.Equal(t, compiled.match(method, reqPath), MatchesPath(pattern, CombinePath(method, reqPath)), "%q %q %q", pattern, method, reqPath)
+			}
+		}
+	}
+
+	require.Zero(t, testing.AllocsPerRun(100, func() { MatchesPath("GET|/users/*", "GET|/users/1") }))
+}
@@ -0,0 +1,177 @@
+package transport
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// pathSet matches a request against many path patterns at once. Segment patterns are merged into a
+// trie walked once per request, regular expressions and catch-alls are kept in a list scanned after
+// it. Matching does not allocate for paths starting with a slash.
+type pathSet struct {
+	root   *trieNode
+	linear []pathPattern // Regular expression and any-path patterns
+}
+
+// trieNode is reached after matching some segments. Edges are tried from the most to the least
+// specific, the request matches when any of them leads to a node accepting its method at the end of
+// the path.
+type trieNode struct {
+	literals map[string]*trieNode
+	param    *trieNode // {name}, one non-empty segment
+	star     *trieNode // *, one segment
+	globs    []globEdge
+	anyDepth *trieNode // **, any number of segments
+
+	end       bool // A pattern ends here
+	anyMethod bool
+	methods   []string
+}
+
+type globEdge struct {
+	pattern string
+	node    *trieNode
+}
+
+func newPathSet(patterns []pathPattern) *pathSet {
+	s := &pathSet{root: &trieNode{}}
+	for idx := range patterns {
+		p := &patterns[idx]
+		switch {
+		case p.err != nil:
+			// Invalid patterns match nothing.
+		case p.template == nil:
+			s.linear = append(s.linear, *p)
+		default:
+			s.root.insert(p.template.segments, p.method)
+		}
+	}
+
+	return s
+}
+
+func (n *trieNode) insert(segments []string, method string) {
+	for _, segment := range segments {
+		n = n.child(segment)
+	}
+
+	n.end = true
+	switch {
+	case method == "":
+		n.anyMethod = true
+	case !slices.Contains(n.methods, method):
+		n.methods = append(n.methods, method)
+	}
+}
+
+// child returns the node after segment, creating it when missing.
+func (n *trieNode) child(segment string) *trieNode {
+	edge := func(next **trieNode) *trieNode {
+		if *next == nil {
+			*next = &trieNode{}
+		}
+
+		return *next
+	}
+
+	switch {
+	case segment == "**":
+		return edge(&n.anyDepth)
+	case segment == ConsCharStar:
+		return edge(&n.star)
+	case isTemplateParam(segment):
+		return edge(&n.param)
+	case strings.ContainsAny(segment, "*?[\\"):
+		for _, glob := range n.globs {
+			if glob.pattern == segment {
+				return glob.node
+			}
+		}
+
+		n.globs = append(n.globs, globEdge{pattern: segment, node: &trieNode{}})
+		return n.globs[len(n.globs)-1].node
+	}
+
+	if n.literals == nil {
+		n.literals = map[string]*trieNode{}
+	}
+
+	if _, ok := n.literals[segment]; !ok {
+		n.literals[segment] = &trieNode{}
+	}
+
+	return n.literals[segment]
+}
+
+func (s *pathSet) match(method, reqPath string) bool {
+	segmentPath := reqPath
+	switch {
+	case reqPath == "":
+		segmentPath = "/"
+	case reqPath[0] != '/':
+		segmentPath = "/" + reqPath
+	}
+
+	if s.root.match(method, segmentPath) {
+		return true
+	}
+
+	for idx := range s.linear {
+		if s.linear[idx].match(method, reqPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match reports whether reqPath, empty or starting with a slash, leads from n to a node accepting method.
+func (n *trieNode) match(method, reqPath string) bool {
+	if reqPath == "" && n.accepts(method) {
+		return true
+	}
+
+	if n.anyDepth != nil {
+		for rest := reqPath; ; {
+			if n.anyDepth.match(method, rest) {
+				return true
+			}
+
+			if rest == "" {
+				break
+			}
+
+			_, rest = nextSegment(rest)
+		}
+	}
+
+	if reqPath == "" {
+		return false
+	}
+
+	segment, rest := nextSegment(reqPath)
+	if next, ok := n.literals[segment]; ok && next.match(method, rest) {
+		return true
+	}
+
+	if n.param != nil && segment != "" && n.param.match(method, rest) {
+		return true
+	}
+
+	if n.star != nil && n.star.match(method, rest) {
+		return true
+	}
+
+	for _, glob := range n.globs {
+		if matched, _ := path.Match(glob.pattern, segment); matched && glob.node.match(method, rest) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *trieNode) accepts(method string) bool {
+	return n.end && (n.anyMethod || slices.Contains(n.methods, method))
+}
@@ -0,0 +1,129 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathSet_MatchesLinearScan(t *testing.T) {
+	patterns := []string{
+		"GET|/users", "GET|/users/*", "POST|/users/{id}/orders", "*|/health", "/files/**/raw",
+		"GET|/v*/items", "DELETE|/a/*/c", "GET|/", `GET|re:^/users/\d+$`, "PUT|*", "/a/{x}/**",
+		"GET|/files/**", "GET|/[ab]/x", `GET|re:(`, "GET|/users/*/orders/{id}",
+	}
+	methods := []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	paths := []string{
+		"", "/", "/users", "/users/", "/users/1", "/users/1/orders", "/users/1/orders/2", "/usersettings",
+		"/health", "/files/raw", "/files/a/b/raw", "/files/a/raw/x", "/v2/items", "/x/items", "/a/b/c",
+		"/a//c", "/a/x", "/a/x/y/z", "/b/x", "users/1", "/users/abc",
+	}
+
+	for n := 1; n <= len(patterns); n++ {
+		compiled := compileAll(patterns[:n], compilePathPattern)
+		set := newPathSet(compiled)
+		for _, method := range methods {
+			for _, reqPath := range paths {
+				expected := false
+				for idx := range compiled {
+					expected = expected || compiled[idx].match(method, reqPath)
+				}
+
+				require.Equal(t, expected, set.match(method, reqPath), "%v %s %s", patterns[:n], method, reqPath)
+			}
+		}
+	}
+}
+
+func TestMatcher_MatchPathAllocations(t *testing.T) {
+	matcher := NewMatcher(MatcherConfig{
+		WhiteListPaths: benchmarkPatterns(100),
+		BlackListPaths: []string{"GET|/internal/**"},
+	})
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/service40/users/7/orders"}}
+
+	require.True(t, matcher.MatchPath(req))
+	require.Zero(t, testing.AllocsPerRun(100, func() { matcher.MatchPath(req) }))
+}
+
+// benchmarkPatterns returns n distinct route patterns in the styles the matcher supports.
+func benchmarkPatterns(n int) []string {
+	patterns := make([]string, n)
+	for idx := range patterns {
+		switch idx % 4 {
+		case 0:
+			patterns[idx] = fmt.Sprintf("GET|/service%d/users/{id}/orders", idx)
+		case 1:
+			patterns[idx] = fmt.Sprintf("POST|/service%d/users/*", idx)
+		case 2:
+			patterns[idx] = fmt.Sprintf("/service%d/health", idx)
+		default:
+			patterns[idx] = fmt.Sprintf("GET|/service%d/files/**/raw", idx)
+		}
+	}
+
+	return patterns
+}
+
+// baselineMatchesPath is MatchesPath as MatcherConfig used it before patterns were compiled: an exact
+// comparison, or a prefix one for patterns ending with /*.
+func baselineMatchesPath(pattern, path string) bool {
+	if pattern == ConsCharStar {
+		return false
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "/*"))
+	}
+
+	return pattern == path
+}
+
+// BenchmarkMatchPath compares formatting METHOD|path and matching each pattern string as MatcherConfig
+// used to, scanning the compiled patterns, and the trie NewMatcher builds. The baseline supports fewer
+// pattern styles and misses the request, it is the cost of the scan alone.
+func BenchmarkMatchPath(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		patterns := benchmarkPatterns(n)
+		// The last pattern of the list, the worst case of a scan.
+		req := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: fmt.Sprintf("/service%d/files/a/b/raw", n-1)}}
+
+		b.Run(fmt.Sprintf("baseline/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				combined := CombinePath(req.Method, req.URL.Path)
+				for _, pattern := range patterns {
+					if baselineMatchesPath(pattern, combined) {
+						break
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("linear/%d", n), func(b *testing.B) {
+			compiled := compileAll(patterns, compilePathPattern)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				for idx := range compiled {
+					if compiled[idx].match(req.Method, req.URL.Path) {
+						break
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("trie/%d", n), func(b *testing.B) {
+			matcher := NewMatcher(MatcherConfig{WhiteListPaths: patterns})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				matcher.MatchPath(req)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	routeOther       = "other"
+	statusClassError = "error"
+)
+
+// MetricLabels are the dimensions every measurement of NewTransportMetrics is recorded with.
+type MetricLabels struct {
+	Method      string
+	Host        string
+	Route       string     // Path normalized by the configured templates, "other" past the route limit
+	StatusClass string     // 2xx, 3xx, 4xx, 5xx, or "error" when no response was received; empty for in-flight
+	ErrorClass  ErrorClass // Set when no response was received
+}
+
+// RequestMetrics are the measurements of one finished request.
+type RequestMetrics struct {
+	Duration     time.Duration // Until the response body was closed
+	RequestSize  int64
+	ResponseSize int64
+}
+
+// MetricsRecorder receives the measurements of NewTransportMetrics, e.g. to feed Prometheus or OpenTelemetry.
+type MetricsRecorder interface {
+	// AddInFlight moves the in-flight gauge by delta; labels have no status.
+	AddInFlight(ctx context.Context, labels MetricLabels, delta int64)
+	// RecordRequest counts a finished request and observes its latency and sizes.
+	RecordRequest(ctx context.Context, labels MetricLabels, metrics RequestMetrics)
+}
+
+type metricsTransport struct {
+	tp       http.RoundTripper
+	config   *metricsConfig
+	matcher  Matcher
+	recorder MetricsRecorder
+	routes   *routeLimiter
+}
+
+type metricsConfig struct {
+	MatcherConfig
+	matcher       Matcher // Replaces MatcherConfig when set
+	recorder      MetricsRecorder
+	routeTemplate []string // Paths matching a template are recorded as the template, e.g. /users/:id
+	maxRoutes     int      // Distinct routes recorded before the rest are grouped as "other", 0 for no limit
+	observer      Observer
+}
+
+var DefaultMetricsConfig = metricsConfig{
+	MatcherConfig: MatcherConfig{WhiteListPaths: []string{ConsCharStar}},
+	maxRoutes:     100,
+}
+
+// NewTransportMetrics records request count, latency, in-flight requests, sizes and error classes.
+// Without MetricsOptionRecorder the measurements are published through expvar.
+func NewTransportMetrics(tp http.RoundTripper, opts ...MetricsOption) http.RoundTripper {
+	cfg := DefaultMetricsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	recorder := cfg.recorder
+	if recorder == nil {
+		recorder = defaultExpvarRecorder()
+	}
+
+	templates := make([]pathTemplate, len(cfg.routeTemplate))
+	for idx, template := range cfg.routeTemplate {
+		templates[idx] = parsePathTemplate(template)
+	}
+
+	return &metricsTransport{
+		tp:       tp,
+		config:   &cfg,
+		matcher:  MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, defaultLogger, LayerMetrics),
+		recorder: recorder,
+		routes:   &routeLimiter{templates: templates, max: cfg.maxRoutes, seen: map[string]struct{}{}},
+	}
+}
+
+func (mt *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !mt.matcher.MatchPath(req) {
+		return mt.tp.RoundTrip(req)
+	}
+
+	end := observeRequest(mt.config.observer, LayerMetrics, req)
+	res, err := mt.roundTrip(req)
+	end(res, err)
+
+	return res, err
+}
+
+func (mt *metricsTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	labels := MetricLabels{
+		Method: req.Method,
+		Host:   req.URL.Host,
+		Route:  mt.routes.route(req.URL.Path),
+	}
+
+	start := time.Now()
+	mt.recorder.AddInFlight(ctx, labels, 1)
+
+	var reqBody *bodyCapture
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = newBodyCapture(req.Body, -1, nil)
+		req = req.WithContext(ctx)
+		req.Body = reqBody
+	}
+
+	// The declared length covers bodies the wrapped transport did not read.
+	requestSize := func() int64 {
+		if reqBody != nil {
+			return max(reqBody.bytesRead(), req.ContentLength)
+		}
+
+		return max(req.ContentLength, 0)
+	}
+
+	res, err := mt.tp.RoundTrip(req)
+	if err != nil {
+		mt.recorder.AddInFlight(ctx, labels, -1)
+		labels.StatusClass, labels.ErrorClass = statusClassError, ClassifyError(err)
+		mt.recorder.RecordRequest(ctx, labels, RequestMetrics{Duration: time.Since(start), RequestSize: requestSize()})
+		return nil, err
+	}
+
+	finish := func(resBody *bodyCapture) {
+		mt.recorder.AddInFlight(ctx, labels, -1)
+		metrics := RequestMetrics{Duration: time.Since(start), RequestSize: requestSize()}
+		if resBody != nil {
+			metrics.ResponseSize = resBody.bytesRead()
+		}
+
+		finished := labels
+		finished.StatusClass = statusClass(res.StatusCode)
+		mt.recorder.RecordRequest(ctx, finished, metrics)
+	}
+
+	if res.Body == nil || res.Body == http.NoBody {
+		finish(nil)
+		return res, nil
+	}
+
+	// The request stays in flight until the caller closes the body.
+	res.Body = newBodyCapture(res.Body, -1, finish)
+
+	return res, nil
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// routeLimiter normalizes paths by template and bounds the number of distinct routes.
+type routeLimiter struct {
+	templates []pathTemplate
+	max       int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func (l *routeLimiter) route(path string) string {
+	route := normalizeRoute(l.templates, path)
+	if l.max <= 0 {
+		return route
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[route]; ok {
+		return route
+	}
+
+	if len(l.seen) >= l.max {
+		return routeOther
+	}
+
+	l.seen[route] = struct{}{}
+
+	return route
+}
@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"context"
+	"expvar"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultExpvarName = "transport_http_client"
+
+// DefaultDurationBuckets are the latency histogram upper bounds in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ExpvarRecorder publishes request metrics as an expvar map, keyed by metric name and then by label set.
+type ExpvarRecorder struct {
+	buckets       []float64
+	requests      *expvar.Map
+	inFlight      *expvar.Map
+	durationSum   *expvar.Map
+	durationCount *expvar.Map
+	durationLE    *expvar.Map
+	requestBytes  *expvar.Map
+	responseBytes *expvar.Map
+}
+
+var defaultExpvarRecorder = sync.OnceValue(func() *ExpvarRecorder {
+	return NewExpvarRecorder(defaultExpvarName)
+})
+
+// NewExpvarRecorder publishes the metrics under name. Recorders created with the same name share the map.
+func NewExpvarRecorder(name string) *ExpvarRecorder {
+	root, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		root = expvar.NewMap(name)
+	}
+
+	child := func(key string) *expvar.Map {
+		if m, ok := root.Get(key).(*expvar.Map); ok {
+			return m
+		}
+
+		m := new(expvar.Map).Init()
+		root.Set(key, m)
+		return m
+	}
+
+	return &ExpvarRecorder{
+		buckets:       DefaultDurationBuckets,
+		requests:      child("requests_total"),
+		inFlight:      child("requests_in_flight"),
+		durationSum:   child("request_duration_seconds_sum"),
+		durationCount: child("request_duration_seconds_count"),
+		durationLE:    child("request_duration_seconds_bucket"),
+		requestBytes:  child("request_size_bytes_sum"),
+		responseBytes: child("response_size_bytes_sum"),
+	}
+}
+
+func (r *ExpvarRecorder) AddInFlight(_ context.Context, labels MetricLabels, delta int64) {
+	r.inFlight.Add(labels.key(), delta)
+}
+
+func (r *ExpvarRecorder) RecordRequest(_ context.Context, labels MetricLabels, metrics RequestMetrics) {
+	key := labels.key()
+	seconds := metrics.Duration.Seconds()
+
+	r.requests.Add(key, 1)
+	r.durationSum.AddFloat(key, seconds)
+	r.durationCount.Add(key, 1)
+	for _, le := range r.buckets {
+		if seconds <= le {
+			r.durationLE.Add(key+`,le="`+strconv.FormatFloat(le, 'g', -1, 64)+`"`, 1)
+		}
+	}
+
+	r.requestBytes.Add(key, metrics.RequestSize)
+	r.responseBytes.Add(key, metrics.ResponseSize)
+}
+
+// key renders the labels like a Prometheus label set, omitting empty ones.
+func (l MetricLabels) key() string {
+	var b strings.Builder
+	for _, pair := range [][2]string{
+		{"method", l.Method},
+		{"host", l.Host},
+		{"route", l.Route},
+		{"status_class", l.StatusClass},
+		{"error_class", string(l.ErrorClass)},
+	} {
+		if pair[1] == "" {
+			continue
+		}
+
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(pair[0] + `="` + pair[1] + `"`)
+	}
+
+	return b.String()
+}
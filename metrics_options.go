@@ -0,0 +1,52 @@
+package transport
+
+type MetricsOption func(*metricsConfig) *metricsConfig
+
+func MetricsOptionMatcherConfig(config MatcherConfig) MetricsOption {
+	return func(c *metricsConfig) *metricsConfig {
+		c.MatcherConfig = config
+		return c
+	}
+}
+
+// MetricsOptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func MetricsOptionMatcher(matcher Matcher) MetricsOption {
+	return func(c *metricsConfig) *metricsConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// MetricsOptionRecorder sets where measurements go, expvar by default.
+func MetricsOptionRecorder(recorder MetricsRecorder) MetricsOption {
+	return func(c *metricsConfig) *metricsConfig {
+		c.recorder = recorder
+		return c
+	}
+}
+
+// MetricsOptionRouteTemplates records paths matching a template as the template, e.g. /users/{id} as /users/:id.
+// Templates are matched like the paths of MatcherConfig patterns.
+func MetricsOptionRouteTemplates(templates []string) MetricsOption {
+	return func(c *metricsConfig) *metricsConfig {
+		c.routeTemplate = templates
+		return c
+	}
+}
+
+// MetricsOptionMaxRoutes bounds the distinct route label values; later routes are recorded as "other".
+func MetricsOptionMaxRoutes(max int) MetricsOption {
+	return func(c *metricsConfig) *metricsConfig {
+		c.maxRoutes = max
+		return c
+	}
+}
+
+// MetricsOptionObserver reports request start and end events of the metrics transport to observer.
+func MetricsOptionObserver(observer Observer) MetricsOption {
+	return func(c *metricsConfig) *metricsConfig {
+		c.observer = observer
+		return c
+	}
+}
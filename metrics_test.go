@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"context"
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testMetricsRecorder struct {
+	mu       sync.Mutex
+	inFlight int64
+	labels   []MetricLabels
+	metrics  []RequestMetrics
+}
+
+func (r *testMetricsRecorder) AddInFlight(_ context.Context, _ MetricLabels, delta int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight += delta
+}
+
+func (r *testMetricsRecorder) RecordRequest(_ context.Context, labels MetricLabels, metrics RequestMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.labels = append(r.labels, labels)
+	r.metrics = append(r.metrics, metrics)
+}
+
+func TestMetricsTransport_Record(t *testing.T) {
+	recorder := &testMetricsRecorder{}
+	client := &http.Client{
+		Transport: NewTransportMetrics(&staticRoundTripper{status: http.StatusCreated, body: "created"},
+			MetricsOptionRecorder(recorder),
+			MetricsOptionRouteTemplates([]string{"/users/{id}"}),
+		),
+	}
+
+	res, err := client.Post(defaultDomain+"/users/42", "text/plain", strings.NewReader("hello"))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, recorder.inFlight, "in flight until the body is closed")
+
+	_, err = io.Copy(io.Discard, res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	require.Zero(t, recorder.inFlight)
+	require.Equal(t, []MetricLabels{{
+		Method:      http.MethodPost,
+		Host:        "example.com",
+		Route:       "/users/:id",
+		StatusClass: "2xx",
+	}}, recorder.labels)
+	require.EqualValues(t, 5, recorder.metrics[0].RequestSize)
+	require.EqualValues(t, 7, recorder.metrics[0].ResponseSize)
+	require.Positive(t, recorder.metrics[0].Duration)
+}
+
+func TestMetricsTransport_Error(t *testing.T) {
+	recorder := &testMetricsRecorder{}
+	client := &http.Client{
+		Transport: NewTransportMetrics(&staticRoundTripper{err: context.DeadlineExceeded}, MetricsOptionRecorder(recorder)),
+	}
+
+	_, err := client.Get(defaultURL)
+	require.Error(t, err)
+
+	require.Zero(t, recorder.inFlight)
+	require.Equal(t, "error", recorder.labels[0].StatusClass)
+	require.Equal(t, ErrorClassTimeout, recorder.labels[0].ErrorClass)
+	require.Equal(t, defaultPath, recorder.labels[0].Route)
+}
+
+func TestMetricsTransport_MaxRoutes(t *testing.T) {
+	recorder := &testMetricsRecorder{}
+	client := &http.Client{
+		Transport: NewTransportMetrics(&staticRoundTripper{status: http.StatusOK},
+			MetricsOptionRecorder(recorder),
+			MetricsOptionMaxRoutes(2),
+		),
+	}
+
+	for _, path := range []string{"/a", "/b", "/c", "/a"} {
+		res, err := client.Get(defaultDomain + path)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	var routes []string
+	for _, labels := range recorder.labels {
+		routes = append(routes, labels.Route)
+	}
+
+	require.Equal(t, []string{"/a", "/b", "other", "/a"}, routes)
+}
+
+func TestExpvarRecorder_Record(t *testing.T) {
+	recorder := NewExpvarRecorder("transport_test_metrics")
+	labels := MetricLabels{Method: http.MethodGet, Host: "example.com", Route: "/v1", StatusClass: "2xx"}
+	recorder.RecordRequest(context.Background(), labels, RequestMetrics{Duration: 20_000_000, ResponseSize: 10})
+	recorder.RecordRequest(context.Background(), labels, RequestMetrics{Duration: 2_000_000_000, ResponseSize: 5})
+
+	root := expvar.Get("transport_test_metrics").(*expvar.Map)
+	key := `method="GET",host="example.com",route="/v1",status_class="2xx"`
+	require.Equal(t, "2", root.Get("requests_total").(*expvar.Map).Get(key).String())
+	require.Equal(t, "15", root.Get("response_size_bytes_sum").(*expvar.Map).Get(key).String())
+	require.Equal(t, "1", root.Get("request_duration_seconds_bucket").(*expvar.Map).Get(key+`,le="0.025"`).String())
+	require.Equal(t, "2", root.Get("request_duration_seconds_bucket").(*expvar.Map).Get(key+`,le="2.5"`).String())
+	require.Same(t, recorder.requests, NewExpvarRecorder("transport_test_metrics").requests)
+}
@@ -0,0 +1,279 @@
+package transport
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Event names, as returned by Event.Name.
+const (
+	EventRequestStart       = "request start"
+	EventRequestEnd         = "request end"
+	EventAttempt            = "attempt"
+	EventRetryScheduled     = "retry scheduled"
+	EventBreakerStateChange = "breaker state change"
+	EventBreakerRejected    = "breaker rejected"
+	EventRateLimited        = "rate limited"
+	EventCacheHit           = "cache hit"
+	EventFaultInjected      = "fault injected"
+)
+
+// Layer names identifying a middleware in request start and end events and in its warnings.
+const (
+	LayerLog            = "log"
+	LayerRetry          = "retry"
+	LayerCircuitBreaker = "circuit_breaker"
+	LayerMetrics        = "metrics"
+	LayerTrace          = "trace"
+	LayerDump           = "dump"
+	LayerHAR            = "har"
+	LayerFault          = "fault"
+)
+
+// Event is raised by a middleware while it handles a request. Use a type switch on the concrete
+// *Event types to read their fields.
+type Event interface {
+	Name() string
+	Attrs() []slog.Attr
+}
+
+// Observer receives the events of every middleware it is given to, through the middleware's
+// observer option or through ContextWithObserver.
+type Observer interface {
+	Observe(ctx context.Context, event Event)
+}
+
+// ObserverFunc adapts a function to Observer.
+type ObserverFunc func(ctx context.Context, event Event)
+
+func (f ObserverFunc) Observe(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// MultiObserver fans events out to every observer in order.
+func MultiObserver(observers ...Observer) Observer {
+	return ObserverFunc(func(ctx context.Context, event Event) {
+		for _, observer := range observers {
+			observer.Observe(ctx, event)
+		}
+	})
+}
+
+type observerKey struct{}
+
+// ContextWithObserver returns a context whose requests report the events of every middleware to
+// observer, in addition to any observer already present. Breaker state changes belong to no request
+// and are not reported to it.
+func ContextWithObserver(ctx context.Context, observer Observer) context.Context {
+	if parent, ok := ctx.Value(observerKey{}).(Observer); ok {
+		observer = MultiObserver(observer, parent)
+	}
+
+	return context.WithValue(ctx, observerKey{}, observer)
+}
+
+// Notify reports event to observer, when not nil, and to the observers in ctx.
+// Middlewares outside this package use it to raise events such as CacheHitEvent.
+func Notify(ctx context.Context, observer Observer, event Event) {
+	if observer != nil {
+		observer.Observe(ctx, event)
+	}
+
+	if ctxObserver, ok := ctx.Value(observerKey{}).(Observer); ok {
+		ctxObserver.Observe(ctx, event)
+	}
+}
+
+type RequestStartEvent struct {
+	Layer   string
+	Request *http.Request
+}
+
+func (e *RequestStartEvent) Name() string {
+	return EventRequestStart
+}
+
+func (e *RequestStartEvent) Attrs() []slog.Attr {
+	return []slog.Attr{slog.String("layer", e.Layer)}
+}
+
+// RequestEndEvent is raised once the layer returns, Duration covers the time until response headers.
+type RequestEndEvent struct {
+	Layer    string
+	Request  *http.Request
+	Response *http.Response // Nil when Err is set
+	Err      error
+	Duration time.Duration
+}
+
+func (e *RequestEndEvent) Name() string {
+	return EventRequestEnd
+}
+
+func (e *RequestEndEvent) Attrs() []slog.Attr {
+	attrs := []slog.Attr{slog.String("layer", e.Layer), slog.Duration("duration", e.Duration)}
+	if e.Response != nil {
+		attrs = append(attrs, slog.Int("status", e.Response.StatusCode))
+	}
+
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()), slog.String("error_class", string(ClassifyError(e.Err))))
+	}
+
+	return attrs
+}
+
+// AttemptEvent is raised by the retry transport before each try.
+type AttemptEvent struct {
+	Request *http.Request
+	Attempt Attempt
+}
+
+func (e *AttemptEvent) Name() string {
+	return EventAttempt
+}
+
+func (e *AttemptEvent) Attrs() []slog.Attr {
+	return []slog.Attr{slog.Int("attempt", e.Attempt.Number), slog.Int("max_attempts", e.Attempt.Max)}
+}
+
+// RetryScheduledEvent is raised by the retry transport when a try failed and Next will follow after Delay.
+type RetryScheduledEvent struct {
+	Request    *http.Request
+	Next       Attempt
+	Delay      time.Duration
+	StatusCode int   // Status of the failed try, 0 when it returned an error
+	Err        error // Error of the failed try
+}
+
+func (e *RetryScheduledEvent) Name() string {
+	return EventRetryScheduled
+}
+
+func (e *RetryScheduledEvent) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int("attempt", e.Next.Number),
+		slog.Int("max_attempts", e.Next.Max),
+		slog.Duration("delay", e.Delay),
+	}
+
+	if e.StatusCode != 0 {
+		attrs = append(attrs, slog.Int("status", e.StatusCode))
+	}
+
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+
+	return attrs
+}
+
+// BreakerStateChangeEvent is raised once per transition of the breaker from one state to another,
+// through gobreaker.Settings.OnStateChange. States are closed, half-open or open. A transition is not
+// owned by one request, so the event only reaches CircuitBreakerOptionObserver, not observers
+// installed with ContextWithObserver. It is raised while the breaker is locked: the observer must not
+// call back into the transport.
+type BreakerStateChangeEvent struct {
+	From string
+	To   string
+}
+
+func (e *BreakerStateChangeEvent) Name() string {
+	return EventBreakerStateChange
+}
+
+func (e *BreakerStateChangeEvent) Attrs() []slog.Attr {
+	return []slog.Attr{slog.String("from", e.From), slog.String("to", e.To)}
+}
+
+// BreakerRejectedEvent is raised by the circuit breaker transport when it refused to send a request.
+type BreakerRejectedEvent struct {
+	Request *http.Request
+	State   string
+	Err     error
+}
+
+func (e *BreakerRejectedEvent) Name() string {
+	return EventBreakerRejected
+}
+
+func (e *BreakerRejectedEvent) Attrs() []slog.Attr {
+	return []slog.Attr{slog.String("state", e.State), slog.String("error", e.Err.Error())}
+}
+
+// RateLimitedEvent is raised by the retry transport when the server answered 429 Too Many Requests.
+type RateLimitedEvent struct {
+	Request    *http.Request
+	StatusCode int
+	RetryAfter time.Duration // From the Retry-After header, 0 when absent
+}
+
+func (e *RateLimitedEvent) Name() string {
+	return EventRateLimited
+}
+
+func (e *RateLimitedEvent) Attrs() []slog.Attr {
+	return []slog.Attr{slog.Int("status", e.StatusCode), slog.Duration("retry_after", e.RetryAfter)}
+}
+
+// CacheHitEvent is raised by caching middlewares serving a response without sending the request.
+type CacheHitEvent struct {
+	Request *http.Request
+	Key     string
+}
+
+func (e *CacheHitEvent) Name() string {
+	return EventCacheHit
+}
+
+func (e *CacheHitEvent) Attrs() []slog.Attr {
+	return []slog.Attr{slog.String("key", e.Key)}
+}
+
+// FaultInjectedEvent is raised by the fault transport for every fault it injects into a request.
+type FaultInjectedEvent struct {
+	Request    *http.Request
+	Kind       FaultKind
+	Delay      time.Duration // Added latency, or hang time of a timeout
+	StatusCode int           // Status of a synthetic response
+	Err        error         // Injected error
+}
+
+func (e *FaultInjectedEvent) Name() string {
+	return EventFaultInjected
+}
+
+func (e *FaultInjectedEvent) Attrs() []slog.Attr {
+	attrs := []slog.Attr{slog.String("kind", string(e.Kind))}
+	if e.Delay > 0 {
+		attrs = append(attrs, slog.Duration("delay", e.Delay))
+	}
+
+	if e.StatusCode != 0 {
+		attrs = append(attrs, slog.Int("status", e.StatusCode))
+	}
+
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+
+	return attrs
+}
+
+// observeRequest raises the start event of a layer and returns the function raising its end event.
+func observeRequest(observer Observer, layer string, req *http.Request) func(res *http.Response, err error) {
+	start := time.Now()
+	Notify(req.Context(), observer, &RequestStartEvent{Layer: layer, Request: req})
+
+	return func(res *http.Response, err error) {
+		Notify(req.Context(), observer, &RequestEndEvent{
+			Layer:    layer,
+			Request:  req,
+			Response: res,
+			Err:      err,
+			Duration: time.Since(start),
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type testObserver struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (o *testObserver) Observe(_ context.Context, event Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *testObserver) names() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	names := make([]string, 0, len(o.events))
+	for _, event := range o.events {
+		names = append(names, event.Name())
+	}
+
+	return names
+}
+
+type retryAfterRoundTripper struct {
+	calls int
+}
+
+func (r *retryAfterRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.calls++
+	if r.calls == 1 {
+		header := http.Header{}
+		header.Set("Retry-After", "2")
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func TestObserver_Retry(t *testing.T) {
+	observer := &testObserver{}
+	client := &http.Client{
+		Transport: NewTransportRetry(&retryAfterRoundTripper{},
+			RetryOptionMaxTries(3),
+			RetryOptionObserver(observer),
+		),
+	}
+
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	require.Equal(t, []string{
+		EventRequestStart,
+		EventAttempt,
+		EventRateLimited,
+		EventRetryScheduled,
+		EventAttempt,
+		EventRequestEnd,
+	}, observer.names())
+
+	rateLimited := observer.events[2].(*RateLimitedEvent)
+	require.Equal(t, 2*time.Second, rateLimited.RetryAfter)
+
+	scheduled := observer.events[3].(*RetryScheduledEvent)
+	require.Equal(t, Attempt{Number: 2, Max: 4}, scheduled.Next)
+	require.Equal(t, http.StatusTooManyRequests, scheduled.StatusCode)
+
+	end := observer.events[5].(*RequestEndEvent)
+	require.Equal(t, LayerRetry, end.Layer)
+	require.Equal(t, http.StatusOK, end.Response.StatusCode)
+}
+
+func TestObserver_Breaker(t *testing.T) {
+	observer, contextObserver := &testObserver{}, &testObserver{}
+	client := &http.Client{
+		Transport: NewCircuitBreakerTransport(&staticRoundTripper{status: http.StatusServiceUnavailable},
+			CircuitBreakerOptionLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			CircuitBreakerOptionObserver(observer),
+			CircuitBreakerOptionBreakerConfig(gobreaker.Settings{
+				ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+				Timeout:     time.Minute,
+			}),
+		),
+	}
+
+	for range 2 {
+		req, err := http.NewRequestWithContext(ContextWithObserver(context.Background(), contextObserver), http.MethodGet, defaultURL, nil)
+		require.NoError(t, err)
+		_, err = client.Do(req)
+		require.Error(t, err)
+	}
+
+	require.Equal(t, []string{
+		EventRequestStart,
+		EventBreakerStateChange,
+		EventRequestEnd,
+		EventRequestStart,
+		EventBreakerRejected,
+		EventRequestEnd,
+	}, observer.names())
+
+	change := observer.events[1].(*BreakerStateChangeEvent)
+	require.Equal(t, "closed", change.From)
+	require.Equal(t, "open", change.To)
+	require.Equal(t, []string{
+		EventRequestStart,
+		EventRequestEnd,
+		EventRequestStart,
+		EventBreakerRejected,
+		EventRequestEnd,
+	}, contextObserver.names(), "state changes belong to no request")
+
+	rejected := observer.events[4].(*BreakerRejectedEvent)
+	require.Equal(t, "open", rejected.State)
+	require.ErrorIs(t, rejected.Err, gobreaker.ErrOpenState)
+}
+
+// blockingRoundTripper answers with status once release is closed.
+type blockingRoundTripper struct {
+	status  int
+	release chan struct{}
+}
+
+func (b *blockingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-b.release
+	return &http.Response{StatusCode: b.status, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func TestObserver_BreakerConcurrentTrip(t *testing.T) {
+	observer := &testObserver{}
+	inner := &blockingRoundTripper{status: http.StatusServiceUnavailable, release: make(chan struct{})}
+	client := &http.Client{
+		Transport: NewCircuitBreakerTransport(inner,
+			CircuitBreakerOptionLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			CircuitBreakerOptionObserver(observer),
+			CircuitBreakerOptionBreakerConfig(gobreaker.Settings{
+				MaxRequests: 10,
+				ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+				Timeout:     time.Minute,
+			}),
+		),
+	}
+
+	var started, done sync.WaitGroup
+	for range 10 {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			started.Done()
+			_, _ = client.Get(defaultURL)
+		}()
+	}
+
+	started.Wait()
+	time.Sleep(10 * time.Millisecond)
+	close(inner.release)
+	done.Wait()
+
+	changes := 0
+	for _, name := range observer.names() {
+		if name == EventBreakerStateChange {
+			changes++
+		}
+	}
+
+	require.Equal(t, 1, changes, "one trip is reported once")
+}
+
+func TestObserver_BreakerCycle(t *testing.T) {
+	observer := &testObserver{}
+	var chained []string
+	client := &http.Client{
+		Transport: NewCircuitBreakerTransport(&sequenceRoundTripper{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}},
+			CircuitBreakerOptionLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			CircuitBreakerOptionObserver(observer),
+			CircuitBreakerOptionBreakerConfig(gobreaker.Settings{
+				ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+				Timeout:     10 * time.Millisecond,
+				OnStateChange: func(_ string, from, to gobreaker.State) {
+					chained = append(chained, from.String()+">"+to.String())
+				},
+			}),
+		),
+	}
+
+	_, err := client.Get(defaultURL)
+	require.Error(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	var changes []string
+	for _, event := range observer.events {
+		if change, ok := event.(*BreakerStateChangeEvent); ok {
+			changes = append(changes, change.From+">"+change.To)
+		}
+	}
+
+	expected := []string{"closed>open", "open>half-open", "half-open>closed"}
+	require.Equal(t, expected, changes)
+	require.Equal(t, expected, chained, "the configured callback still runs")
+}
+
+func TestObserver_Context(t *testing.T) {
+	option, first, second := &testObserver{}, &testObserver{}, &testObserver{}
+	client := &http.Client{
+		Transport: NewTransportLog(
+			NewTransportMetrics(&staticRoundTripper{status: http.StatusOK},
+				MetricsOptionRecorder(&testMetricsRecorder{}),
+				MetricsOptionObserver(option),
+			),
+			LogOptionLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		),
+	}
+
+	ctx := ContextWithObserver(context.Background(), MultiObserver(first))
+	ctx = ContextWithObserver(ctx, second)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, defaultURL, nil)
+	require.NoError(t, err)
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	layers := func(o *testObserver) []string {
+		var layers []string
+		for _, event := range o.events {
+			switch e := event.(type) {
+			case *RequestStartEvent:
+				layers = append(layers, "start "+e.Layer)
+			case *RequestEndEvent:
+				layers = append(layers, "end "+e.Layer)
+			}
+		}
+		return layers
+	}
+
+	all := []string{"start log", "start metrics", "end metrics", "end log"}
+	require.Equal(t, all, layers(first))
+	require.Equal(t, all, layers(second))
+	require.Equal(t, []string{"start metrics", "end metrics"}, layers(option))
+}
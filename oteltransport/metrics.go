@@ -0,0 +1,105 @@
+package oteltransport
+
+import (
+	"context"
+
+	"github.com/dangnmh/transport"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MetricsRecorder is a transport.MetricsRecorder backed by OpenTelemetry instruments named after the
+// HTTP client semantic conventions. The request count is the count of the duration histogram.
+type MetricsRecorder struct {
+	duration     metric.Float64Histogram
+	active       metric.Int64UpDownCounter
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+}
+
+type metricsConfig struct {
+	meterProvider metric.MeterProvider
+}
+
+var DefaultMetricsConfig = metricsConfig{}
+
+// NewMetricsRecorder creates the instruments from the configured meter provider, the global one by default.
+func NewMetricsRecorder(opts ...MetricsOption) (*MetricsRecorder, error) {
+	cfg := DefaultMetricsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.meterProvider == nil {
+		cfg.meterProvider = otel.GetMeterProvider()
+	}
+
+	meter := cfg.meterProvider.Meter(instrumentationName)
+
+	var (
+		r   MetricsRecorder
+		err error
+	)
+
+	if r.duration, err = meter.Float64Histogram("http.client.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP client requests until the response body was closed."),
+		metric.WithExplicitBucketBoundaries(transport.DefaultDurationBuckets...),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.active, err = meter.Int64UpDownCounter("http.client.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of active HTTP requests."),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.requestSize, err = meter.Int64Histogram("http.client.request.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP client request bodies."),
+	); err != nil {
+		return nil, err
+	}
+
+	if r.responseSize, err = meter.Int64Histogram("http.client.response.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP client response bodies read."),
+	); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (r *MetricsRecorder) AddInFlight(ctx context.Context, labels transport.MetricLabels, delta int64) {
+	r.active.Add(ctx, delta, metric.WithAttributes(metricAttributes(labels)...))
+}
+
+func (r *MetricsRecorder) RecordRequest(ctx context.Context, labels transport.MetricLabels, metrics transport.RequestMetrics) {
+	attrs := metric.WithAttributes(metricAttributes(labels)...)
+
+	r.duration.Record(ctx, metrics.Duration.Seconds(), attrs)
+	r.requestSize.Record(ctx, metrics.RequestSize, attrs)
+	r.responseSize.Record(ctx, metrics.ResponseSize, attrs)
+}
+
+func metricAttributes(labels transport.MetricLabels) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", labels.Method),
+		attribute.String("server.address", labels.Host),
+		attribute.String("url.template", labels.Route),
+	}
+
+	if labels.StatusClass != "" {
+		attrs = append(attrs, attribute.String("transport.status_class", labels.StatusClass))
+	}
+
+	if labels.ErrorClass != transport.ErrorClassNone {
+		attrs = append(attrs, attribute.String("error.type", string(labels.ErrorClass)))
+	}
+
+	return attrs
+}
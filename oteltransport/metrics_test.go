@@ -0,0 +1,49 @@
+package oteltransport
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/dangnmh/transport"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricsRecorder_Record(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	recorder, err := NewMetricsRecorder(MetricsOptionMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	require.NoError(t, err)
+
+	mock := &mockRoundTripper{statuses: []int{http.StatusOK}}
+	client := &http.Client{
+		Transport: transport.NewTransportMetrics(mock, transport.MetricsOptionRecorder(recorder)),
+	}
+
+	for range 3 {
+		res, err := client.Get("http://example.com/v1/api")
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+
+	duration := metrics["http.client.request.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	require.EqualValues(t, 3, duration.DataPoints[0].Count)
+
+	status, ok := duration.DataPoints[0].Attributes.Value(attribute.Key("transport.status_class"))
+	require.True(t, ok)
+	require.Equal(t, "2xx", status.AsString())
+
+	active := metrics["http.client.active_requests"].(metricdata.Sum[int64])
+	require.Zero(t, active.DataPoints[0].Value)
+}
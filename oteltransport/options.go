@@ -0,0 +1,71 @@
+package oteltransport
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/dangnmh/transport"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Option func(*config) *config
+
+// MetricsOption configures NewMetricsRecorder.
+type MetricsOption func(*metricsConfig) *metricsConfig
+
+// OptionTracerProvider sets the provider spans are created with, the global one by default.
+func OptionTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) *config {
+		c.tracerProvider = provider
+		return c
+	}
+}
+
+// OptionPropagator sets the propagator injecting the span into request headers, the global one by default.
+func OptionPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) *config {
+		c.propagator = propagator
+		return c
+	}
+}
+
+func OptionSpanNameFormatter(format func(req *http.Request) string) Option {
+	return func(c *config) *config {
+		c.spanName = format
+		return c
+	}
+}
+
+func OptionMatcherConfig(matcherConfig transport.MatcherConfig) Option {
+	return func(c *config) *config {
+		c.MatcherConfig = matcherConfig
+		return c
+	}
+}
+
+// OptionLogger sets the logger warning about invalid MatcherConfig patterns, slog.Default() by default.
+func OptionLogger(logger *slog.Logger) Option {
+	return func(c *config) *config {
+		c.logger = logger
+		return c
+	}
+}
+
+// OptionMatcher selects the traced requests with matcher, built with combinators such as
+// transport.And, instead of MatcherConfig.
+func OptionMatcher(matcher transport.Matcher) Option {
+	return func(c *config) *config {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// MetricsOptionMeterProvider sets the provider NewMetricsRecorder creates instruments with, the global one by default.
+func MetricsOptionMeterProvider(provider metric.MeterProvider) MetricsOption {
+	return func(c *metricsConfig) *metricsConfig {
+		c.meterProvider = provider
+		return c
+	}
+}
@@ -0,0 +1,195 @@
+// Package oteltransport traces requests made through the transport middlewares with OpenTelemetry.
+package oteltransport
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/dangnmh/transport"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/dangnmh/transport/oteltransport"
+	layer               = "otel" // Names the transport in its warnings
+)
+
+// Span attributes recorded besides the HTTP semantic conventions.
+const (
+	AttributeRetryCount   = attribute.Key("transport.retry.count")
+	AttributeBreakerState = attribute.Key("transport.breaker.state")
+	AttributeRequestID    = attribute.Key("transport.request_id")
+)
+
+type otelTransport struct {
+	tp      http.RoundTripper
+	config  *config
+	matcher transport.Matcher
+	tracer  trace.Tracer
+}
+
+type config struct {
+	transport.MatcherConfig
+	matcher        transport.Matcher // Replaces MatcherConfig when set
+	logger         *slog.Logger      // Receives the warnings about invalid MatcherConfig patterns
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	spanName       func(req *http.Request) string
+}
+
+var DefaultConfig = config{
+	MatcherConfig: transport.MatcherConfig{WhiteListPaths: []string{transport.ConsCharStar}},
+	logger:        slog.Default(),
+	spanName: func(req *http.Request) string {
+		return "HTTP " + req.Method
+	},
+}
+
+type hookKey struct{}
+
+// NewTransport creates a client span per request. Placed outside NewTransportRetry the span covers
+// the logical request; placed inside it, each attempt gets a child span tagged with its resend count.
+// Stacking both gives a logical span with one child per attempt. Retry and circuit breaker events are
+// recorded as span events on the innermost span of the layer raising them, except breaker state
+// changes, which belong to no request.
+func NewTransport(tp http.RoundTripper, opts ...Option) http.RoundTripper {
+	cfg := DefaultConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = otel.GetTracerProvider()
+	}
+
+	if cfg.propagator == nil {
+		cfg.propagator = otel.GetTextMapPropagator()
+	}
+
+	return &otelTransport{
+		tp:      tp,
+		config:  &cfg,
+		matcher: transport.MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, cfg.logger, layer),
+		tracer:  cfg.tracerProvider.Tracer(instrumentationName),
+	}
+}
+
+func (ot *otelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !ot.matcher.MatchPath(req) {
+		return ot.tp.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	if ctx.Value(hookKey{}) == nil {
+		ctx = context.WithValue(transport.ContextWithObserver(ctx, transport.ObserverFunc(recordEvent)), hookKey{}, true)
+	}
+
+	ctx, span := ot.tracer.Start(ctx, ot.config.spanName(req),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(requestAttributes(req)...),
+	)
+	defer span.End()
+
+	if sc := span.SpanContext(); sc.IsValid() {
+		ctx = transport.ContextWithTraceContext(ctx, transport.TraceContext{
+			TraceID: sc.TraceID(),
+			SpanID:  sc.SpanID(),
+			Flags:   byte(sc.TraceFlags()),
+			State:   sc.TraceState().String(),
+		})
+	}
+
+	req = req.Clone(ctx)
+	ot.config.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := ot.tp.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("error.type", string(transport.ClassifyError(err))))
+		return res, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+		span.SetAttributes(attribute.String("error.type", strconv.Itoa(res.StatusCode)))
+	}
+
+	return res, nil
+}
+
+func requestAttributes(req *http.Request) []attribute.KeyValue {
+	u := *req.URL
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", u.String()),
+		attribute.String("server.address", req.URL.Hostname()),
+	}
+
+	if port := req.URL.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, attribute.Int("server.port", n))
+		}
+	}
+
+	if attempt, ok := transport.AttemptFromContext(req.Context()); ok && attempt.Number > 1 {
+		attrs = append(attrs, attribute.Int("http.request.resend_count", attempt.Number-1))
+	}
+
+	if id := transport.RequestIDFromContext(req.Context()); id != "" {
+		attrs = append(attrs, AttributeRequestID.String(id))
+	}
+
+	return attrs
+}
+
+// recordEvent adds a middleware event to the span current in ctx. Request start, end and attempt
+// events are left out as the span and its children already describe them.
+func recordEvent(ctx context.Context, event transport.Event) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	switch e := event.(type) {
+	case *transport.RequestStartEvent, *transport.RequestEndEvent, *transport.AttemptEvent:
+		return
+	case *transport.RetryScheduledEvent:
+		span.SetAttributes(AttributeRetryCount.Int(e.Next.Number - 1))
+	case *transport.BreakerRejectedEvent:
+		span.SetAttributes(AttributeBreakerState.String(e.State))
+	}
+
+	attrs := event.Attrs()
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kvs = append(kvs, slogAttribute(a))
+	}
+
+	span.AddEvent(event.Name(), trace.WithAttributes(kvs...))
+}
+
+func slogAttribute(a slog.Attr) attribute.KeyValue {
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindBool:
+		return attribute.Bool(a.Key, value.Bool())
+	case slog.KindInt64:
+		return attribute.Int64(a.Key, value.Int64())
+	case slog.KindUint64:
+		return attribute.Int64(a.Key, int64(value.Uint64()))
+	case slog.KindFloat64:
+		return attribute.Float64(a.Key, value.Float64())
+	default:
+		return attribute.String(a.Key, value.String())
+	}
+}
@@ -0,0 +1,147 @@
+package oteltransport
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dangnmh/transport"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type mockRoundTripper struct {
+	statuses []int
+	errs     []error
+	calls    int
+	reqs     []*http.Request
+}
+
+func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer func() {
+		m.calls++
+	}()
+
+	m.reqs = append(m.reqs, req)
+	if m.calls < len(m.errs) && m.errs[m.calls] != nil {
+		return nil, m.errs[m.calls]
+	}
+
+	status := m.statuses[min(m.calls, len(m.statuses)-1)]
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func newRecorder() (*tracetest.SpanRecorder, []Option) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	return recorder, []Option{
+		OptionTracerProvider(provider),
+		OptionPropagator(propagation.TraceContext{}),
+	}
+}
+
+func TestTransport_RetryAttempts(t *testing.T) {
+	recorder, options := newRecorder()
+	mock := &mockRoundTripper{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	client := &http.Client{
+		Transport: NewTransport(
+			transport.NewTransportRetry(NewTransport(mock, options...)),
+			options...,
+		),
+	}
+
+	res, err := client.Get("http://example.com:8080/v1/api?token=secret")
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	first, second, logical := spans[0], spans[1], spans[2]
+
+	require.Equal(t, logical.SpanContext().SpanID(), first.Parent().SpanID())
+	require.Equal(t, logical.SpanContext().SpanID(), second.Parent().SpanID())
+	require.Equal(t, codes.Error, first.Status().Code)
+	require.Equal(t, codes.Unset, second.Status().Code)
+
+	attrs := attributeMap(logical)
+	require.Equal(t, "GET", attrs["http.request.method"])
+	require.Equal(t, "http://example.com:8080/v1/api", attrs["url.full"])
+	require.Equal(t, int64(8080), attrs["server.port"])
+	require.Equal(t, int64(http.StatusOK), attrs["http.response.status_code"])
+	require.Equal(t, int64(1), attrs[string(AttributeRetryCount)])
+	require.NotContains(t, attributeMap(first), "http.request.resend_count")
+	require.Equal(t, int64(1), attributeMap(second)["http.request.resend_count"])
+
+	require.Len(t, logical.Events(), 1)
+	require.Equal(t, transport.EventRetryScheduled, logical.Events()[0].Name)
+
+	// Each attempt propagates its own span.
+	tc, ok := transport.TraceContextFromHeader(mock.reqs[1].Header)
+	require.True(t, ok)
+	require.Equal(t, [8]byte(second.SpanContext().SpanID()), tc.SpanID)
+}
+
+func TestTransport_Error(t *testing.T) {
+	recorder, options := newRecorder()
+	mock := &mockRoundTripper{errs: []error{errors.New("boom")}}
+	client := &http.Client{Transport: NewTransport(mock, options...)}
+
+	_, err := client.Get("http://example.com/v1/api")
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, "unknown", attributeMap(spans[0])["error.type"])
+}
+
+func TestTransport_BreakerRejected(t *testing.T) {
+	recorder, options := newRecorder()
+	mock := &mockRoundTripper{errs: []error{errors.New("boom")}, statuses: []int{http.StatusOK}}
+	client := &http.Client{
+		Transport: NewTransport(
+			transport.NewCircuitBreakerTransport(mock,
+				transport.CircuitBreakerOptionLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+				transport.CircuitBreakerOptionBreakerConfig(gobreaker.Settings{
+					Timeout: time.Minute,
+					ReadyToTrip: func(counts gobreaker.Counts) bool {
+						return counts.ConsecutiveFailures >= 1
+					},
+				}),
+			),
+			options...,
+		),
+	}
+
+	for range 2 {
+		_, err := client.Get("http://example.com/v1/api")
+		require.Error(t, err)
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for _, event := range spans[0].Events() {
+		require.NotEqual(t, transport.EventBreakerStateChange, event.Name, "transitions belong to no span")
+	}
+
+	require.Equal(t, transport.EventBreakerRejected, spans[1].Events()[0].Name)
+	require.Equal(t, "open", attributeMap(spans[1])[string(AttributeBreakerState)])
+	require.Equal(t, 1, mock.calls)
+}
+
+func attributeMap(span sdktrace.ReadOnlySpan) map[string]any {
+	ans := map[string]any{}
+	for _, kv := range span.Attributes() {
+		ans[string(kv.Key)] = kv.Value.AsInterface()
+	}
+
+	return ans
+}
@@ -0,0 +1,25 @@
+package promtransport
+
+type Option func(*config) *config
+
+// OptionNamespace sets the metric name prefix, http_client by default.
+func OptionNamespace(namespace string) Option {
+	return func(c *config) *config {
+		c.namespace = namespace
+		return c
+	}
+}
+
+func OptionDurationBuckets(buckets []float64) Option {
+	return func(c *config) *config {
+		c.durationBuckets = buckets
+		return c
+	}
+}
+
+func OptionSizeBuckets(buckets []float64) Option {
+	return func(c *config) *config {
+		c.sizeBuckets = buckets
+		return c
+	}
+}
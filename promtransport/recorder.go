@@ -0,0 +1,95 @@
+// Package promtransport records the metrics of transport.NewTransportMetrics with the Prometheus client.
+package promtransport
+
+import (
+	"context"
+
+	"github.com/dangnmh/transport"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestLabels  = []string{"method", "host", "route", "status_class", "error_class"}
+	inFlightLabels = []string{"method", "host", "route"}
+)
+
+// Recorder is a transport.MetricsRecorder backed by Prometheus collectors.
+type Recorder struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     *prometheus.GaugeVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+type config struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+}
+
+var DefaultConfig = config{
+	namespace:       "http_client",
+	durationBuckets: transport.DefaultDurationBuckets,
+	sizeBuckets:     prometheus.ExponentialBuckets(128, 4, 8),
+}
+
+// NewRecorder creates the collectors and registers them with reg.
+func NewRecorder(reg prometheus.Registerer, opts ...Option) (*Recorder, error) {
+	cfg := DefaultConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	r := &Recorder{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "requests_total",
+			Help:      "Requests made, by route, status class and error class.",
+		}, requestLabels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Request latency until the response body was closed.",
+			Buckets:   cfg.durationBuckets,
+		}, requestLabels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: cfg.namespace,
+			Name:      "requests_in_flight",
+			Help:      "Requests sent whose response body is not closed yet.",
+		}, inFlightLabels),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "request_size_bytes",
+			Help:      "Request body sizes.",
+			Buckets:   cfg.sizeBuckets,
+		}, requestLabels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "response_size_bytes",
+			Help:      "Response body bytes read.",
+			Buckets:   cfg.sizeBuckets,
+		}, requestLabels),
+	}
+
+	for _, collector := range []prometheus.Collector{r.requests, r.duration, r.inFlight, r.requestSize, r.responseSize} {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Recorder) AddInFlight(_ context.Context, labels transport.MetricLabels, delta int64) {
+	r.inFlight.WithLabelValues(labels.Method, labels.Host, labels.Route).Add(float64(delta))
+}
+
+func (r *Recorder) RecordRequest(_ context.Context, labels transport.MetricLabels, metrics transport.RequestMetrics) {
+	values := []string{labels.Method, labels.Host, labels.Route, labels.StatusClass, string(labels.ErrorClass)}
+
+	r.requests.WithLabelValues(values...).Inc()
+	r.duration.WithLabelValues(values...).Observe(metrics.Duration.Seconds())
+	r.requestSize.WithLabelValues(values...).Observe(float64(metrics.RequestSize))
+	r.responseSize.WithLabelValues(values...).Observe(float64(metrics.ResponseSize))
+}
@@ -0,0 +1,56 @@
+package promtransport
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dangnmh/transport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type staticRoundTripper struct {
+	status int
+}
+
+func (s *staticRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: s.status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("body")),
+		Request:    req,
+	}, nil
+}
+
+func TestRecorder_Record(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	recorder, err := NewRecorder(reg, OptionNamespace("test"))
+	require.NoError(t, err)
+
+	client := &http.Client{
+		Transport: transport.NewTransportMetrics(&staticRoundTripper{status: http.StatusServiceUnavailable},
+			transport.MetricsOptionRecorder(recorder),
+			transport.MetricsOptionRouteTemplates([]string{"/users/{id}"}),
+		),
+	}
+
+	for range 2 {
+		res, err := client.Get("http://example.com/users/42")
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(recorder.requests.WithLabelValues("GET", "example.com", "/users/:id", "5xx", "")))
+	require.Equal(t, 0.0, testutil.ToFloat64(recorder.inFlight.WithLabelValues("GET", "example.com", "/users/:id")))
+	require.Equal(t, 1, testutil.CollectAndCount(recorder.duration))
+
+	require.Equal(t, 1, testutil.CollectAndCount(recorder.responseSize))
+
+	_, err = NewRecorder(reg, OptionNamespace("test"))
+	require.Error(t, err, "duplicate registration")
+}
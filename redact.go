@@ -0,0 +1,431 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// RedactConfig describes which parts of an exchange are replaced by [REDACTED] before being written out.
+type RedactConfig struct {
+	Headers      []string         // Header names, case-insensitive
+	QueryParams  []string         // Query parameter names, case-insensitive
+	BodyKeys     []string         // JSON keys at any depth, case-insensitive
+	BodyPaths    []string         // JSONPath-like selectors, e.g. $.user.password or $.cards[*].number
+	FormFields   []string         // Form-encoded field names, case-insensitive
+	BodyPatterns []*regexp.Regexp // Applied to the body text after key, path and field redaction
+}
+
+var (
+	RedactPatternCardNumber = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	RedactPatternEmail      = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+)
+
+var DefaultRedactConfig = RedactConfig{
+	Headers:     []string{"Authorization", "Cookie", "Set-Cookie"},
+	QueryParams: []string{"api_key", "apikey", "token", "access_token", "sig", "signature", "password"},
+	BodyKeys:    []string{"password", "secret", "token", "access_token", "refresh_token"},
+	FormFields:  []string{"password", "secret", "token", "access_token", "refresh_token"},
+}
+
+// RedactHeader returns a copy of header with sensitive values replaced, for middlewares outside this
+// package. It compiles c on each call, use NewRedactor to redact many exchanges.
+func (c RedactConfig) RedactHeader(header http.Header) http.Header {
+	return NewRedactor(c).RedactHeader(header)
+}
+
+// RedactURL returns a copy of u without userinfo credentials and with sensitive query parameters redacted.
+func (c RedactConfig) RedactURL(u *url.URL) *url.URL {
+	return NewRedactor(c).RedactURL(u)
+}
+
+// RedactBody redacts a body according to its content type, like the log transport does.
+func (c RedactConfig) RedactBody(contentType string, body []byte) []byte {
+	return NewRedactor(c).RedactBody(contentType, body)
+}
+
+// Redactor applies a RedactConfig compiled once, safe for concurrent use.
+type Redactor struct {
+	r *redactor
+}
+
+// NewRedactor compiles cfg for middlewares outside this package redacting many exchanges.
+func NewRedactor(cfg RedactConfig) *Redactor {
+	return &Redactor{r: newRedactor(cfg)}
+}
+
+// RedactHeader returns a copy of header with sensitive values replaced.
+func (r *Redactor) RedactHeader(header http.Header) http.Header {
+	return http.Header(r.r.sanitizeHeaders(header))
+}
+
+// RedactURL returns a copy of u without userinfo credentials and with sensitive query parameters redacted.
+func (r *Redactor) RedactURL(u *url.URL) *url.URL {
+	return r.r.redactURL(u)
+}
+
+// RedactBody redacts a body according to its content type, like the log transport does.
+func (r *Redactor) RedactBody(contentType string, body []byte) []byte {
+	return r.r.redactBody(contentType, body)
+}
+
+type redactor struct {
+	headers  []string
+	query    []string
+	keys     []string
+	paths    [][]jsonPathSegment
+	fields   []string
+	patterns []*regexp.Regexp
+}
+
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+	any     bool
+}
+
+func newRedactor(cfg RedactConfig) *redactor {
+	r := &redactor{
+		headers:  StringLowers(cfg.Headers),
+		query:    StringLowers(cfg.QueryParams),
+		keys:     StringLowers(cfg.BodyKeys),
+		fields:   StringLowers(cfg.FormFields),
+		patterns: cfg.BodyPatterns,
+	}
+
+	for _, path := range cfg.BodyPaths {
+		r.paths = append(r.paths, parseJSONPath(path))
+	}
+
+	return r
+}
+
+// parseJSONPath parses selectors like $.a.b, a[*].b or $.a[2] into segments.
+func parseJSONPath(path string) []jsonPathSegment {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+
+	var segments []jsonPathSegment
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == ConsCharStar {
+			segments = append(segments, jsonPathSegment{any: true})
+		} else if key != "" {
+			segments = append(segments, jsonPathSegment{key: key})
+		}
+
+		for rest != "" {
+			var index string
+			index, rest, _ = strings.Cut(rest, "]")
+			rest = strings.TrimPrefix(rest, "[")
+			if index == ConsCharStar {
+				segments = append(segments, jsonPathSegment{isIndex: true, any: true})
+				continue
+			}
+
+			n, err := strconv.Atoi(index)
+			if err != nil {
+				segments = append(segments, jsonPathSegment{key: strings.Trim(index, `'"`)})
+				continue
+			}
+
+			segments = append(segments, jsonPathSegment{isIndex: true, index: n})
+		}
+	}
+
+	return segments
+}
+
+func (s jsonPathSegment) matches(other jsonPathSegment) bool {
+	if s.isIndex != other.isIndex && !(s.any && !s.isIndex) {
+		return false
+	}
+
+	if s.any {
+		return true
+	}
+
+	if s.isIndex {
+		return s.index == other.index
+	}
+
+	return s.key == other.key
+}
+
+func (r *redactor) sanitizeHeaders(headers http.Header) map[string][]string {
+	sanitized := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		if r.redactsHeader(key) {
+			sanitized[key] = []string{redactedValue}
+		} else {
+			sanitized[key] = values
+		}
+	}
+
+	return sanitized
+}
+
+// redactsHeader reports whether the values of the named header are redacted.
+func (r *redactor) redactsHeader(name string) bool {
+	return slices.Contains(r.headers, strings.ToLower(name))
+}
+
+func (r *redactor) redactQuery(query url.Values) url.Values {
+	sanitized := make(url.Values, len(query))
+	for key, values := range query {
+		if slices.Contains(r.query, strings.ToLower(key)) {
+			sanitized[key] = []string{redactedValue}
+		} else {
+			sanitized[key] = values
+		}
+	}
+
+	return sanitized
+}
+
+// redactURL returns a copy of u without userinfo credentials and with sensitive query parameters redacted.
+func (r *redactor) redactURL(u *url.URL) *url.URL {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User("REDACTED")
+	}
+
+	if redacted.RawQuery != "" {
+		redacted.RawQuery = r.redactQuery(redacted.Query()).Encode()
+	}
+
+	return &redacted
+}
+
+// redactBody redacts a body, or a prefix of one, according to its content type.
+func (r *redactor) redactBody(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		body = r.redactForm(body)
+	case strings.Contains(mediaType, "json") || mediaType == "" || mediaType == "text/plain":
+		if redacted, ok := r.redactJSON(body); ok {
+			body = redacted
+		}
+	}
+
+	for _, pattern := range r.patterns {
+		body = pattern.ReplaceAll(body, []byte(redactedValue))
+	}
+
+	return body
+}
+
+// redactPart redacts the value of a multipart field: entirely when its name is a sensitive field or
+// key, otherwise like a body of the part content type, so JSON keys and body patterns still apply.
+func (r *redactor) redactPart(name, contentType string, value []byte) string {
+	name = strings.ToLower(name)
+	if slices.Contains(r.fields, name) || slices.Contains(r.keys, name) {
+		return redactedValue
+	}
+
+	return string(r.redactBody(contentType, value))
+}
+
+// redactForm replaces the values of sensitive fields while keeping the original order and encoding.
+// body is left unchanged.
+func (r *redactor) redactForm(body []byte) []byte {
+	if len(r.fields) == 0 {
+		return body
+	}
+
+	pairs := bytes.Split(body, []byte("&"))
+	for idx, pair := range pairs {
+		key, _, hasValue := bytes.Cut(pair, []byte("="))
+		name, err := url.QueryUnescape(string(key))
+		if err != nil || !hasValue || !slices.Contains(r.fields, strings.ToLower(name)) {
+			continue
+		}
+
+		// key shares the array of body, the redacted pair must not be appended to it.
+		pairs[idx] = slices.Concat(key, []byte("="+redactedValue))
+	}
+
+	return bytes.Join(pairs, []byte("&"))
+}
+
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	count     int
+}
+
+// redactJSON walks the JSON token stream and re-encodes it compactly with sensitive values replaced.
+// Truncated input is tolerated: everything up to the last complete token is returned.
+// It reports false when body does not start with a JSON value.
+func (r *redactor) redactJSON(body []byte) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil, false
+	}
+
+	if len(r.keys) == 0 && len(r.paths) == 0 {
+		return body, true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+
+	var (
+		out    bytes.Buffer
+		stack  []*jsonFrame
+		path   []jsonPathSegment
+		truncd bool
+	)
+
+	// done marks the current value of the enclosing container as written.
+	done := func() {
+		if len(stack) == 0 {
+			return
+		}
+
+		top := stack[len(stack)-1]
+		top.count++
+		top.expectKey = top.object
+		path = path[:len(path)-1]
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			truncd = err != io.EOF
+			break
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			out.WriteByte(byte(delim))
+			stack = stack[:len(stack)-1]
+			done()
+			continue
+		}
+
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.object && top.expectKey {
+				if top.count > 0 {
+					out.WriteByte(',')
+				}
+
+				key, _ := tok.(string)
+				writeJSONValue(&out, key)
+				out.WriteByte(':')
+				path = append(path, jsonPathSegment{key: key})
+				top.expectKey = false
+				continue
+			}
+
+			if !top.object {
+				if top.count > 0 {
+					out.WriteByte(',')
+				}
+
+				path = append(path, jsonPathSegment{isIndex: true, index: top.count})
+			}
+		}
+
+		if len(stack) > 0 && r.matchesJSONPath(path) {
+			writeJSONValue(&out, redactedValue)
+			if delim, ok := tok.(json.Delim); ok && (delim == '{' || delim == '[') {
+				if !skipJSONValue(dec) {
+					truncd = true
+					break
+				}
+			}
+
+			done()
+			continue
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			out.WriteByte(byte(delim))
+			stack = append(stack, &jsonFrame{object: delim == '{', expectKey: delim == '{'})
+			continue
+		}
+
+		writeJSONValue(&out, tok)
+		done()
+	}
+
+	if truncd && out.Len() == 0 {
+		return nil, false
+	}
+
+	return out.Bytes(), true
+}
+
+func (r *redactor) matchesJSONPath(path []jsonPathSegment) bool {
+	last := path[len(path)-1]
+	if !last.isIndex && slices.Contains(r.keys, strings.ToLower(last.key)) {
+		return true
+	}
+
+	for _, selector := range r.paths {
+		if len(selector) != len(path) {
+			continue
+		}
+
+		matched := true
+		for idx, segment := range selector {
+			if !segment.matches(path[idx]) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipJSONValue consumes the rest of an object or array whose opening delimiter was already read.
+func skipJSONValue(dec *json.Decoder) bool {
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+
+	return true
+}
+
+func writeJSONValue(buf *bytes.Buffer, value any) {
+	if number, ok := value.(json.Number); ok {
+		buf.WriteString(number.String())
+		return
+	}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(value)
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+}
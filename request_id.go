@@ -0,0 +1,46 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context carrying the request ID shared by every middleware layer.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set by ContextWithRequestID, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random 128-bit hex identifier.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ensureRequestID returns req and its request ID, taken from the context, then the X-Request-ID header,
+// or generated. When the context did not carry it yet, a copy of req carrying the ID is returned so
+// inner layers see the same value.
+func ensureRequestID(req *http.Request) (*http.Request, string) {
+	if id := RequestIDFromContext(req.Context()); id != "" {
+		return req, id
+	}
+
+	id := req.Header.Get(HeaderRequestID)
+	if id == "" {
+		id = NewRequestID()
+	}
+
+	return req.WithContext(ContextWithRequestID(req.Context(), id)), id
+}
@@ -2,11 +2,17 @@ package transport
 
 import (
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/cenkalti/backoff/v4"
 )
 
+// discardBodyLimit bounds how much of a retried response is read before closing it.
+const discardBodyLimit = 4 << 10
+
 type retryTransport struct {
 	tp      http.RoundTripper
 	config  *retryConfig
@@ -17,6 +23,8 @@ type retryConfig struct {
 	RetryOnError bool
 	MaxTries     uint64 // Maximum number of retry attempts.
 	MatcherConfig
+	matcher  Matcher // Replaces MatcherConfig when set
+	observer Observer
 }
 
 var DefaultRetryConfig = retryConfig{
@@ -34,7 +42,7 @@ func NewTransportRetry(tp http.RoundTripper, opts ...RetryOption) http.RoundTrip
 	return &retryTransport{
 		tp:      tp,
 		config:  &cfg,
-		matcher: NewMatcher(cfg.MatcherConfig),
+		matcher: MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, defaultLogger, LayerRetry),
 	}
 }
 
@@ -43,12 +51,18 @@ func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		return rt.tp.RoundTrip(req)
 	}
 
-	cloneReq, err := cloneRequest(req)
-	if err != nil {
-		return nil, err
-	}
+	req, _ = ensureRequestID(req)
+	end := observeRequest(rt.config.observer, LayerRetry, req)
+	res, err := rt.roundTrip(req)
+	end(res, err)
 
-	res, err := rt.tp.RoundTrip(cloneReq)
+	return res, err
+}
+
+func (rt *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	attempt := Attempt{Max: rt.maxAttempts()}
+
+	res, outcome, err := rt.try(req, &attempt)
 	if rt.config.MaxTries == 0 {
 		return res, err
 	}
@@ -57,34 +71,130 @@ func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		return res, err
 	}
 
-	if res != nil && !rt.matcher.Match(req, res.StatusCode) {
+	if !MatchOutcome(rt.matcher, req, outcome) {
 		return res, err
 	}
 
 	bo := backoff.NewExponentialBackOff()
-
-	var lastSuccessRes *http.Response
-	res, err = backoff.RetryWithData(func() (*http.Response, error) {
-		cloneReq, err := cloneRequest(req)
-		if err != nil {
-			return nil, err
+	rt.retryScheduled(req, attempt, res, err, 0)
+
+	// pending is the latest response, its body is released once a newer response replaces it.
+	pending := res
+	var lastSuccessRes, lastTryRes *http.Response
+	var lastTryErr error
+	res, err = backoff.RetryNotifyWithData(func() (*http.Response, error) {
+		res, outcome, err := rt.try(req, &attempt)
+		lastTryRes, lastTryErr = res, err
+		matched := MatchOutcome(rt.matcher, req, outcome)
+		if err != nil && !matched {
+			return nil, backoff.Permanent(err)
 		}
 
-		res, err := rt.tp.RoundTrip(cloneReq)
 		if err != nil {
 			return nil, err
 		}
 
-		lastSuccessRes = res
-		if rt.matcher.Match(req, res.StatusCode) {
+		discardBody(pending)
+		pending, lastSuccessRes = res, res
+		if matched {
 			return nil, errors.New("bad status")
 		}
 
 		return res, err
-	}, backoff.WithMaxRetries(bo, rt.config.MaxTries))
+	}, backoff.WithMaxRetries(bo, rt.config.MaxTries), func(err error, delay time.Duration) {
+		rt.retryScheduled(req, attempt, lastTryRes, lastTryErr, delay)
+	})
 	if err != nil && lastSuccessRes != nil {
 		return lastSuccessRes, nil
 	}
 
+	if pending != res {
+		discardBody(pending)
+	}
+
 	return res, err
 }
+
+// discardBody drains a little of the body of a response that is not returned, so its connection can
+// be reused, and closes it.
+func discardBody(res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, discardBodyLimit))
+	_ = res.Body.Close()
+}
+
+// maxAttempts returns the upper bound of tries: the first one plus up to MaxTries+1 in the backoff loop.
+func (rt *retryTransport) maxAttempts() int {
+	if rt.config.MaxTries == 0 {
+		return 1
+	}
+
+	return int(rt.config.MaxTries) + 2
+}
+
+// try sends the next attempt of req, tagging its context with the attempt number and counting it
+// for layers outside the retry transport.
+func (rt *retryTransport) try(req *http.Request, attempt *Attempt) (*http.Response, Outcome, error) {
+	cloneReq, err := cloneRequest(req)
+	if err != nil {
+		return nil, newOutcome(nil, err, 0), err
+	}
+
+	attempt.Number++
+	if counter := attemptCounterFromContext(req.Context()); counter != nil {
+		counter.n.Add(1)
+	}
+
+	cloneReq = cloneReq.WithContext(contextWithAttempt(cloneReq.Context(), *attempt))
+	Notify(req.Context(), rt.config.observer, &AttemptEvent{Request: cloneReq, Attempt: *attempt})
+
+	start := time.Now()
+	res, err := rt.tp.RoundTrip(cloneReq)
+	outcome := newOutcome(res, err, time.Since(start))
+	if res != nil && res.StatusCode == http.StatusTooManyRequests {
+		Notify(req.Context(), rt.config.observer, &RateLimitedEvent{
+			Request:    cloneReq,
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		})
+	}
+
+	return res, outcome, err
+}
+
+// retryScheduled reports that the attempt after last will be made once delay elapsed.
+// The failed try is described by res when it got a response, by err otherwise.
+func (rt *retryTransport) retryScheduled(req *http.Request, last Attempt, res *http.Response, err error, delay time.Duration) {
+	event := &RetryScheduledEvent{
+		Request: req,
+		Next:    Attempt{Number: last.Number + 1, Max: last.Max},
+		Delay:   delay,
+		Err:     err,
+	}
+
+	if res != nil {
+		event.StatusCode = res.StatusCode
+	}
+
+	Notify(req.Context(), rt.config.observer, event)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
@@ -26,3 +26,20 @@ func RetryOptionMatcherConfig(config MatcherConfig) RetryOption {
 		return c
 	}
 }
+
+// RetryOptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func RetryOptionMatcher(matcher Matcher) RetryOption {
+	return func(c *retryConfig) *retryConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// RetryOptionObserver reports attempts, scheduled retries and rate limiting to observer.
+func RetryOptionObserver(observer Observer) RetryOption {
+	return func(c *retryConfig) *retryConfig {
+		c.observer = observer
+		return c
+	}
+}
@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"path"
+	"strings"
+)
+
+// pathTemplate is a parsed path template such as /users/{id}/orders, shared by path patterns and the
+// paths masked in logs and metrics.
+//
+// Segments written as {name} match one non-empty segment, * matches any single segment, ** any number
+// of them, none included, and others match literally or as a path.Match glob such as v*. A trailing
+// /* matches the path and everything below it, like /**.
+type pathTemplate struct {
+	segments []string
+	err      error // Set when a glob is malformed, the template then matches nothing
+}
+
+func parsePathTemplate(template string) pathTemplate {
+	t := pathTemplate{segments: strings.Split(strings.TrimPrefix(template, "/"), "/")}
+	if last := len(t.segments) - 1; t.segments[last] == ConsCharStar {
+		t.segments[last] = "**"
+	}
+
+	for _, segment := range t.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			t.err = err
+			break
+		}
+	}
+
+	return t
+}
+
+func isTemplateParam(segment string) bool {
+	return len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}'
+}
+
+// match reports whether reqPath matches the template.
+func (t *pathTemplate) match(reqPath string) bool {
+	return t.err == nil && walkSegments(t.segments, withLeadingSlash(reqPath), nil)
+}
+
+// normalize rewrites reqPath with every segment matching a {name} parameter replaced by :name.
+// It reports false when reqPath does not match the template.
+func (t *pathTemplate) normalize(reqPath string) (string, bool) {
+	out := make([]byte, 0, len(reqPath))
+	if t.err != nil || !walkSegments(t.segments, withLeadingSlash(reqPath), &out) {
+		return "", false
+	}
+
+	return string(out), true
+}
+
+// normalizeRoute rewrites path with the first matching template, or returns it unchanged.
+func normalizeRoute(templates []pathTemplate, path string) string {
+	for idx := range templates {
+		if normalized, ok := templates[idx].normalize(path); ok {
+			return normalized
+		}
+	}
+
+	return path
+}
+
+func withLeadingSlash(reqPath string) string {
+	if strings.HasPrefix(reqPath, "/") {
+		return reqPath
+	}
+
+	return "/" + reqPath
+}
+
+// walkSegments matches pattern segments against a path starting with a slash. When out is not nil,
+// the matched path is appended to it with template parameters rewritten; it is left in an unspecified
+// state when pattern does not match.
+func walkSegments(pattern []string, reqPath string, out *[]byte) bool {
+	for idx, segment := range pattern {
+		if segment == "**" {
+			rest := pattern[idx+1:]
+			for {
+				mark := outLen(out)
+				if walkSegments(rest, reqPath, out) {
+					return true
+				}
+
+				if reqPath == "" {
+					return false
+				}
+
+				var value string
+				value, reqPath = nextSegment(reqPath)
+				if out != nil {
+					*out = append(append((*out)[:mark], '/'), value...)
+				}
+			}
+		}
+
+		if reqPath == "" {
+			return false
+		}
+
+		var value string
+		value, reqPath = nextSegment(reqPath)
+		if !matchSegment(segment, value) {
+			return false
+		}
+
+		if out != nil {
+			if isTemplateParam(segment) {
+				*out = append(append(*out, "/:"...), segment[1:len(segment)-1]...)
+			} else {
+				*out = append(append(*out, '/'), value...)
+			}
+		}
+	}
+
+	return reqPath == ""
+}
+
+func outLen(out *[]byte) int {
+	if out == nil {
+		return 0
+	}
+
+	return len(*out)
+}
+
+// nextSegment splits /a/b into a and /b.
+func nextSegment(reqPath string) (string, string) {
+	reqPath = reqPath[1:]
+	if idx := strings.IndexByte(reqPath, '/'); idx >= 0 {
+		return reqPath[:idx], reqPath[idx:]
+	}
+
+	return reqPath, ""
+}
+
+func matchSegment(pattern, value string) bool {
+	if isTemplateParam(pattern) {
+		return value != ""
+	}
+
+	if !strings.ContainsAny(pattern, "*?[\\") {
+		return pattern == value
+	}
+
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
@@ -0,0 +1,24 @@
+package transport
+
+import "log/slog"
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	From int
+	To   int
+}
+
+// StatusClass returns the range covering a whole status class, e.g. StatusClass(5) is 500-599.
+func StatusClass(class int) StatusRange {
+	return StatusRange{From: class * 100, To: class*100 + 99}
+}
+
+func (r StatusRange) Contains(status int) bool {
+	return status >= r.From && status <= r.To
+}
+
+// StatusLevel maps a range of status codes to a log level.
+type StatusLevel struct {
+	StatusRange
+	Level slog.Level
+}
@@ -0,0 +1,198 @@
+package transport
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestTiming collects the connection timing of one request through an httptrace.ClientTrace.
+// It is safe to read while the request is in flight.
+type RequestTiming struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+	wasIdle      bool
+	idleTime     time.Duration
+	remoteAddr   string
+	localAddr    string
+}
+
+// WithRequestTiming returns a shallow copy of req whose context carries a trace feeding the returned timing.
+// Traces already present in the context keep being called.
+func WithRequestTiming(req *http.Request) (*http.Request, *RequestTiming) {
+	timing := &RequestTiming{start: time.Now()}
+	ctx := httptrace.WithClientTrace(req.Context(), timing.ClientTrace())
+
+	return req.WithContext(ctx), timing
+}
+
+// ClientTrace returns the hooks recording into t.
+func (t *RequestTiming) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.set(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.set(&t.dnsDone)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(string, string, error) {
+			t.set(&t.connectDone)
+		},
+		TLSHandshakeStart: func() {
+			t.set(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.set(&t.tlsDone)
+		},
+		GotConn: t.gotConnInfo,
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.set(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.set(&t.firstByte)
+		},
+	}
+}
+
+func (t *RequestTiming) set(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = time.Now()
+}
+
+func (t *RequestTiming) gotConnInfo(info httptrace.GotConnInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gotConn = time.Now()
+	t.reused = info.Reused
+	t.wasIdle = info.WasIdle
+	t.idleTime = info.IdleTime
+	if info.Conn != nil {
+		t.remoteAddr = info.Conn.RemoteAddr().String()
+		t.localAddr = info.Conn.LocalAddr().String()
+	}
+}
+
+func span(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+// DNS returns the time spent resolving the host, zero when no lookup happened.
+func (t *RequestTiming) DNS() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return span(t.dnsStart, t.dnsDone)
+}
+
+// Connect returns the time spent establishing the TCP connection, zero for reused connections.
+func (t *RequestTiming) Connect() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return span(t.connectStart, t.connectDone)
+}
+
+// TLSHandshake returns the time spent in the TLS handshake, zero for plain or reused connections.
+func (t *RequestTiming) TLSHandshake() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return span(t.tlsStart, t.tlsDone)
+}
+
+// GetConn returns the time until a connection was available, including dial and handshake.
+func (t *RequestTiming) GetConn() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return span(t.start, t.gotConn)
+}
+
+// TimeToFirstByte returns the time from the start of the request to the first response byte.
+func (t *RequestTiming) TimeToFirstByte() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return span(t.start, t.firstByte)
+}
+
+// Reused reports whether the request was sent on a previously used connection.
+func (t *RequestTiming) Reused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.reused
+}
+
+// WasIdle reports whether the connection was taken from the idle pool, and for how long it idled.
+func (t *RequestTiming) WasIdle() (bool, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.wasIdle, t.idleTime
+}
+
+// RemoteAddr returns the address of the peer the request was sent to.
+func (t *RequestTiming) RemoteAddr() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.remoteAddr
+}
+
+// LocalAddr returns the local address of the connection the request was sent on.
+func (t *RequestTiming) LocalAddr() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.localAddr
+}
+
+func (t *RequestTiming) LogValue() slog.Value {
+	wasIdle, idleTime := t.WasIdle()
+	attrs := []slog.Attr{
+		slog.Duration("dns", t.DNS()),
+		slog.Duration("connect", t.Connect()),
+		slog.Duration("tls", t.TLSHandshake()),
+		slog.Duration("get_conn", t.GetConn()),
+		slog.Duration("ttfb", t.TimeToFirstByte()),
+		slog.Bool("reused", t.Reused()),
+		slog.Bool("was_idle", wasIdle),
+	}
+
+	if wasIdle {
+		attrs = append(attrs, slog.Duration("idle_time", idleTime))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// connLogValue renders the addresses of the connection the request used.
+func (t *RequestTiming) connLogValue() slog.Value {
+	remoteAddr := t.RemoteAddr()
+	remoteIP, _, _ := net.SplitHostPort(remoteAddr)
+
+	return slog.GroupValue(
+		slog.String("remote_ip", remoteIP),
+		slog.String("remote_addr", remoteAddr),
+		slog.String("local_addr", t.LocalAddr()),
+		slog.Bool("reused", t.Reused()),
+	)
+}
@@ -0,0 +1,224 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderTraceparent = "Traceparent"
+	HeaderTracestate  = "Tracestate"
+	HeaderB3TraceID   = "X-B3-TraceId"
+	HeaderB3SpanID    = "X-B3-SpanId"
+	HeaderB3ParentID  = "X-B3-ParentSpanId"
+	HeaderB3Sampled   = "X-B3-Sampled"
+)
+
+// TraceFlagsSampled is the sampled bit of the W3C trace flags.
+const TraceFlagsSampled byte = 0x01
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceContext is the W3C Trace Context of a span: https://www.w3.org/TR/trace-context/.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	State   string // Raw tracestate header value
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a context carrying tc as the current span.
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the current span set by ContextWithTraceContext.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok && tc.IsValid()
+}
+
+// TraceContextFromHeader reads the traceparent and tracestate headers, e.g. of an incoming server request.
+func TraceContextFromHeader(header http.Header) (TraceContext, bool) {
+	tc, err := ParseTraceparent(header.Get(HeaderTraceparent))
+	if err != nil {
+		return TraceContext{}, false
+	}
+
+	tc.State = header.Get(HeaderTracestate)
+
+	return tc, true
+}
+
+// ParseTraceparent parses a version 00 traceparent header value.
+func ParseTraceparent(value string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	var tc TraceContext
+	var flags [1]byte
+	if !decodeHex(tc.TraceID[:], parts[1]) || !decodeHex(tc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	return tc, nil
+}
+
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+
+	return err == nil
+}
+
+// IsValid reports whether both IDs are non-zero.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&TraceFlagsSampled != 0
+}
+
+func (tc TraceContext) TraceIDString() string {
+	return hex.EncodeToString(tc.TraceID[:])
+}
+
+func (tc TraceContext) SpanIDString() string {
+	return hex.EncodeToString(tc.SpanID[:])
+}
+
+// Traceparent returns the traceparent header value.
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceIDString() + "-" + tc.SpanIDString() + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// NewTraceContext starts a sampled root span with random IDs.
+func NewTraceContext() TraceContext {
+	tc := TraceContext{Flags: TraceFlagsSampled}
+	for !tc.IsValid() {
+		_, _ = rand.Read(tc.TraceID[:])
+		_, _ = rand.Read(tc.SpanID[:])
+	}
+
+	return tc
+}
+
+// Child returns a span of the same trace with a new span ID.
+func (tc TraceContext) Child() TraceContext {
+	child := tc
+	for child.SpanID == tc.SpanID || child.SpanID == [8]byte{} {
+		_, _ = rand.Read(child.SpanID[:])
+	}
+
+	return child
+}
+
+type traceTransport struct {
+	tp      http.RoundTripper
+	config  *traceConfig
+	matcher Matcher
+}
+
+type traceConfig struct {
+	MatcherConfig
+	matcher          Matcher // Replaces MatcherConfig when set
+	propagateB3      bool    // Also write X-B3-* headers
+	setRequestID     bool    // Set X-Request-ID when missing
+	overwriteHeaders bool    // Replace a traceparent the caller already set
+	observer         Observer
+}
+
+var DefaultTraceConfig = traceConfig{
+	MatcherConfig: MatcherConfig{WhiteListPaths: []string{ConsCharStar}},
+	setRequestID:  true,
+}
+
+// NewTransportTrace propagates W3C trace context. Each request becomes a client span, a child of the
+// span in its context or a new root, written to the traceparent and tracestate headers and put in the
+// context seen by inner transports, so NewTransportLog can log its trace_id and span_id.
+func NewTransportTrace(tp http.RoundTripper, opts ...TraceOption) http.RoundTripper {
+	cfg := DefaultTraceConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &traceTransport{
+		tp:      tp,
+		config:  &cfg,
+		matcher: MatcherOrConfig(cfg.matcher, cfg.MatcherConfig, defaultLogger, LayerTrace),
+	}
+}
+
+func (tt *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !tt.matcher.MatchPath(req) {
+		return tt.tp.RoundTrip(req)
+	}
+
+	end := observeRequest(tt.config.observer, LayerTrace, req)
+	res, err := tt.roundTrip(req)
+	end(res, err)
+
+	return res, err
+}
+
+func (tt *traceTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	req, requestID := ensureRequestID(req)
+
+	tc, hasHeader := TraceContextFromHeader(req.Header)
+	if !hasHeader || tt.config.overwriteHeaders {
+		tc = tt.clientSpan(req.Context())
+	}
+
+	parent, hasParent := TraceContextFromContext(req.Context())
+
+	req = req.Clone(ContextWithTraceContext(req.Context(), tc))
+	req.Header.Set(HeaderTraceparent, tc.Traceparent())
+	if tc.State != "" {
+		req.Header.Set(HeaderTracestate, tc.State)
+	}
+
+	if tt.config.propagateB3 {
+		req.Header.Set(HeaderB3TraceID, tc.TraceIDString())
+		req.Header.Set(HeaderB3SpanID, tc.SpanIDString())
+		if hasParent && parent.TraceID == tc.TraceID && parent.SpanID != tc.SpanID {
+			req.Header.Set(HeaderB3ParentID, parent.SpanIDString())
+		}
+
+		if tc.Sampled() {
+			req.Header.Set(HeaderB3Sampled, "1")
+		} else {
+			req.Header.Set(HeaderB3Sampled, "0")
+		}
+	}
+
+	if tt.config.setRequestID && req.Header.Get(HeaderRequestID) == "" {
+		req.Header.Set(HeaderRequestID, requestID)
+	}
+
+	return tt.tp.RoundTrip(req)
+}
+
+func (tt *traceTransport) clientSpan(ctx context.Context) TraceContext {
+	if parent, ok := TraceContextFromContext(ctx); ok {
+		return parent.Child()
+	}
+
+	return NewTraceContext()
+}
@@ -0,0 +1,51 @@
+package transport
+
+type TraceOption func(*traceConfig) *traceConfig
+
+func TraceOptionMatcherConfig(config MatcherConfig) TraceOption {
+	return func(c *traceConfig) *traceConfig {
+		c.MatcherConfig = config
+		return c
+	}
+}
+
+// TraceOptionMatcher selects requests with matcher, built with combinators such as And and StatusIn,
+// instead of MatcherConfig.
+func TraceOptionMatcher(matcher Matcher) TraceOption {
+	return func(c *traceConfig) *traceConfig {
+		c.matcher = matcher
+		return c
+	}
+}
+
+// TraceOptionB3 also writes the X-B3-* headers for Zipkin-style consumers.
+func TraceOptionB3(enable bool) TraceOption {
+	return func(c *traceConfig) *traceConfig {
+		c.propagateB3 = enable
+		return c
+	}
+}
+
+// TraceOptionRequestID sets X-Request-ID from the request ID in the context, or a new one, when missing.
+func TraceOptionRequestID(enable bool) TraceOption {
+	return func(c *traceConfig) *traceConfig {
+		c.setRequestID = enable
+		return c
+	}
+}
+
+// TraceOptionOverwrite replaces a traceparent header the caller already set instead of propagating it.
+func TraceOptionOverwrite(enable bool) TraceOption {
+	return func(c *traceConfig) *traceConfig {
+		c.overwriteHeaders = enable
+		return c
+	}
+}
+
+// TraceOptionObserver reports request start and end events of the trace transport to observer.
+func TraceOptionObserver(observer Observer) TraceOption {
+	return func(c *traceConfig) *traceConfig {
+		c.observer = observer
+		return c
+	}
+}
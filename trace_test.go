@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type headerRoundTripper struct {
+	req *http.Request
+}
+
+func (h *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.req = req
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func TestParseTraceparent_Cases(t *testing.T) {
+	valid := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tc, err := ParseTraceparent(valid)
+	require.NoError(t, err)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceIDString())
+	require.Equal(t, "00f067aa0ba902b7", tc.SpanIDString())
+	require.True(t, tc.Sampled())
+	require.Equal(t, valid, tc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ParseTraceparent(invalid)
+		require.ErrorIs(t, err, ErrInvalidTraceparent, invalid)
+	}
+}
+
+func TestTraceTransport_NewRoot(t *testing.T) {
+	inner := &headerRoundTripper{}
+	client := &http.Client{Transport: NewTransportTrace(inner, TraceOptionB3(true))}
+
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	tc, ok := TraceContextFromHeader(inner.req.Header)
+	require.True(t, ok)
+	require.True(t, tc.Sampled())
+	require.Equal(t, tc.TraceIDString(), inner.req.Header.Get(HeaderB3TraceID))
+	require.Equal(t, tc.SpanIDString(), inner.req.Header.Get(HeaderB3SpanID))
+	require.Empty(t, inner.req.Header.Get(HeaderB3ParentID))
+	require.NotEmpty(t, inner.req.Header.Get(HeaderRequestID))
+
+	fromCtx, ok := TraceContextFromContext(inner.req.Context())
+	require.True(t, ok)
+	require.Equal(t, tc.Traceparent(), fromCtx.Traceparent())
+}
+
+func TestTraceTransport_ChildOfContext(t *testing.T) {
+	inner := &headerRoundTripper{}
+	client := &http.Client{Transport: NewTransportTrace(inner, TraceOptionB3(true))}
+
+	parent := NewTraceContext()
+	parent.State = "vendor=abc"
+	ctx := ContextWithRequestID(ContextWithTraceContext(context.Background(), parent), "req-1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, defaultURL, nil)
+	require.NoError(t, err)
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	tc, ok := TraceContextFromHeader(inner.req.Header)
+	require.True(t, ok)
+	require.Equal(t, parent.TraceID, tc.TraceID)
+	require.NotEqual(t, parent.SpanID, tc.SpanID)
+	require.Equal(t, "vendor=abc", inner.req.Header.Get(HeaderTracestate))
+	require.Equal(t, parent.SpanIDString(), inner.req.Header.Get(HeaderB3ParentID))
+	require.Equal(t, "req-1", inner.req.Header.Get(HeaderRequestID))
+	require.Empty(t, req.Header.Get(HeaderTraceparent), "caller request untouched")
+}
+
+func TestTraceTransport_KeepsCallerHeader(t *testing.T) {
+	inner := &headerRoundTripper{}
+	client := &http.Client{Transport: NewTransportTrace(inner, TraceOptionRequestID(false))}
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+	req, err := http.NewRequest(http.MethodGet, defaultURL, nil)
+	require.NoError(t, err)
+	req.Header.Set(HeaderTraceparent, traceparent)
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	require.Equal(t, traceparent, inner.req.Header.Get(HeaderTraceparent))
+	require.Empty(t, inner.req.Header.Get(HeaderRequestID))
+}
+
+func TestTraceTransport_LogTraceIDs(t *testing.T) {
+	handler := &testLogHandler{}
+	inner := &headerRoundTripper{}
+	client := &http.Client{
+		Transport: NewTransportTrace(NewTransportLog(inner,
+			LogOptionLogger(slog.New(handler)),
+			LogOptionMatcherConfig(logAllMatcherConfig),
+		)),
+	}
+
+	res, err := client.Get(defaultURL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	tc, ok := TraceContextFromHeader(inner.req.Header)
+	require.True(t, ok)
+	attrs := handler.attrs(0)
+	require.Equal(t, tc.TraceIDString(), attrs["trace_id"].String())
+	require.Equal(t, tc.SpanIDString(), attrs["span_id"].String())
+	require.Equal(t, inner.req.Header.Get(HeaderRequestID), attrs["request_id"].String())
+}
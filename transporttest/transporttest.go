@@ -0,0 +1,300 @@
+// Package transporttest provides a scriptable fake http.RoundTripper for testing code built on the
+// transport middlewares without starting servers.
+package transporttest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dangnmh/transport"
+)
+
+// ErrUnexpectedRequest is returned for requests no route was scripted for.
+var ErrUnexpectedRequest = errors.New("transporttest: unexpected request")
+
+// Step is one scripted outcome of a route: a response, or an error when Err is set.
+type Step struct {
+	Status int // 200 when zero
+	Header http.Header
+	Body   string
+	Err    error
+	Delay  time.Duration // Waited before answering, cut short when the request context is done
+}
+
+// Respond scripts a response with status and body.
+func Respond(status int, body string) Step {
+	return Step{Status: status, Body: body}
+}
+
+// Fail scripts a transport error.
+func Fail(err error) Step {
+	return Step{Err: err}
+}
+
+// WithDelay returns s answering after d.
+func (s Step) WithDelay(d time.Duration) Step {
+	s.Delay = d
+	return s
+}
+
+// WithHeader returns s with a response header added.
+func (s Step) WithHeader(key, value string) Step {
+	s.Header = s.Header.Clone()
+	if s.Header == nil {
+		s.Header = http.Header{}
+	}
+
+	s.Header.Add(key, value)
+	return s
+}
+
+// Request is a request received by the RoundTripper, with its body read.
+type Request struct {
+	Method    string
+	URL       *url.URL
+	Header    http.Header
+	Body      []byte
+	Route     string            // Route that answered, empty for unexpected requests
+	Attempt   transport.Attempt // Set when sent through NewTransportRetry
+	RequestID string            // Request ID propagated by the middlewares, if any
+	Time      time.Time
+}
+
+type route struct {
+	pattern string
+	matcher transport.Matcher // Compiled pattern
+	steps   []Step
+	calls   int
+}
+
+// RoundTripper answers requests with the steps scripted per route and records every request.
+// It is safe for concurrent use.
+type RoundTripper struct {
+	mu       sync.Mutex
+	routes   []*route
+	requests []Request
+	bodies   []*body
+}
+
+func New() *RoundTripper {
+	return &RoundTripper{}
+}
+
+// On scripts the answers to the requests matching pattern, one step per request in order, the last
+// step repeating once the others are used. A pattern has the [METHOD|]path form of
+// transport.MatcherConfig paths, like "GET|/users/1"; a path ending in /* matches by prefix and "*"
+// matches every request. Routes are tried in the order they were added.
+func (rt *RoundTripper) On(pattern string, steps ...Step) *RoundTripper {
+	r := &route{pattern: pattern, matcher: transport.PathMatches(pattern), steps: steps}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.routes = append(rt.routes, r)
+
+	return rt
+}
+
+func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded := Request{
+		Method:    req.Method,
+		URL:       req.URL,
+		Header:    req.Header.Clone(),
+		RequestID: transport.RequestIDFromContext(req.Context()),
+		Time:      time.Now(),
+	}
+	recorded.Attempt, _ = transport.AttemptFromContext(req.Context())
+
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		recorded.Body = data
+	}
+
+	rt.mu.Lock()
+	step, r := rt.next(req)
+	if r != nil {
+		recorded.Route = r.pattern
+	}
+	rt.requests = append(rt.requests, recorded)
+	rt.mu.Unlock()
+
+	if r == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrUnexpectedRequest, req.Method, req.URL)
+	}
+
+	if step.Delay > 0 {
+		timer := time.NewTimer(step.Delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if step.Err != nil {
+		return nil, step.Err
+	}
+
+	status := step.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := step.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	b := &body{Reader: bytes.NewReader([]byte(step.Body)), request: req.Method + " " + req.URL.String()}
+	rt.mu.Lock()
+	rt.bodies = append(rt.bodies, b)
+	rt.mu.Unlock()
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          b,
+		ContentLength: int64(len(step.Body)),
+		Request:       req,
+	}, nil
+}
+
+// next returns the step answering req and its route, nil when no route matches. rt.mu is held.
+func (rt *RoundTripper) next(req *http.Request) (Step, *route) {
+	for _, r := range rt.routes {
+		if !r.matcher.MatchPath(req) {
+			continue
+		}
+
+		r.calls++
+		if len(r.steps) == 0 {
+			return Step{}, r
+		}
+
+		return r.steps[min(r.calls, len(r.steps))-1], r
+	}
+
+	return Step{}, nil
+}
+
+// Requests returns the requests received so far, unexpected ones included.
+func (rt *RoundTripper) Requests() []Request {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return append([]Request(nil), rt.requests...)
+}
+
+// Calls returns how many requests the route scripted with pattern answered.
+func (rt *RoundTripper) Calls(pattern string) int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	calls := 0
+	for _, r := range rt.routes {
+		if r.pattern == pattern {
+			calls += r.calls
+		}
+	}
+
+	return calls
+}
+
+// OpenBodies returns the requests whose response body was not closed yet.
+func (rt *RoundTripper) OpenBodies() []string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	var open []string
+	for _, b := range rt.bodies {
+		if !b.isClosed() {
+			open = append(open, b.request)
+		}
+	}
+
+	return open
+}
+
+// AssertAttempts fails t unless the route scripted with pattern answered want requests.
+func (rt *RoundTripper) AssertAttempts(t testing.TB, pattern string, want int) bool {
+	t.Helper()
+	if got := rt.Calls(pattern); got != want {
+		t.Errorf("transporttest: %q answered %d requests, want %d", pattern, got, want)
+		return false
+	}
+
+	return true
+}
+
+// AssertNoUnexpected fails t if a request matched no route.
+func (rt *RoundTripper) AssertNoUnexpected(t testing.TB) bool {
+	t.Helper()
+	ok := true
+	for _, req := range rt.Requests() {
+		if req.Route == "" {
+			t.Errorf("transporttest: unexpected request %s %s", req.Method, req.URL)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// AssertBodiesClosed fails t if a returned response body was not closed.
+func (rt *RoundTripper) AssertBodiesClosed(t testing.TB) bool {
+	t.Helper()
+	open := rt.OpenBodies()
+	for _, request := range open {
+		t.Errorf("transporttest: response body of %s was not closed", request)
+	}
+
+	return len(open) == 0
+}
+
+// CheckBodiesClosed registers AssertBodiesClosed to run when t completes and returns rt.
+func (rt *RoundTripper) CheckBodiesClosed(t testing.TB) *RoundTripper {
+	t.Helper()
+	t.Cleanup(func() {
+		rt.AssertBodiesClosed(t)
+	})
+
+	return rt
+}
+
+// body is a response body remembering whether it was closed.
+type body struct {
+	*bytes.Reader
+	request string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *body) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *body) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
@@ -0,0 +1,139 @@
+package transporttest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dangnmh/transport"
+	"github.com/dangnmh/transport/transporttest"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTB counts failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors int
+}
+
+func (r *recordingTB) Errorf(string, ...any) {
+	r.errors++
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRoundTripper_Script(t *testing.T) {
+	fake := transporttest.New().
+		On("GET|/users/*", transporttest.Respond(http.StatusOK, `{"id":1}`).WithHeader("Content-Type", "application/json")).
+		On("POST|/users", transporttest.Respond(http.StatusCreated, ""), transporttest.Respond(http.StatusConflict, "")).
+		CheckBodiesClosed(t)
+	client := &http.Client{Transport: fake}
+
+	res, err := client.Get("http://example.com/users/1")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":1}`, string(data))
+	require.NoError(t, res.Body.Close())
+
+	for _, status := range []int{http.StatusCreated, http.StatusConflict, http.StatusConflict} {
+		res, err := client.Post("http://example.com/users", "application/json", strings.NewReader(`{"name":"bob"}`))
+		require.NoError(t, err)
+		require.Equal(t, status, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	}
+
+	_, err = client.Get("http://example.com/orders")
+	require.ErrorIs(t, err, transporttest.ErrUnexpectedRequest)
+
+	fake.AssertAttempts(t, "POST|/users", 3)
+	requests := fake.Requests()
+	require.Len(t, requests, 5)
+	require.Equal(t, `{"name":"bob"}`, string(requests[1].Body))
+	require.Equal(t, "", requests[4].Route)
+}
+
+func TestRoundTripper_Retry(t *testing.T) {
+	fake := transporttest.New().
+		On("*",
+			transporttest.Fail(errors.New("connection reset")),
+			transporttest.Respond(http.StatusServiceUnavailable, ""),
+			transporttest.Respond(http.StatusOK, "ok"),
+		).
+		CheckBodiesClosed(t)
+
+	client := &http.Client{
+		Transport: transport.NewTransportLog(
+			transport.NewTransportRetry(fake, transport.RetryOptionMaxTries(5)),
+			transport.LogOptionLogger(discardLogger),
+		),
+	}
+
+	res, err := client.Get("http://example.com/jobs")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, res.Body.Close())
+
+	fake.AssertAttempts(t, "*", 3)
+	fake.AssertNoUnexpected(t)
+
+	requests := fake.Requests()
+	for idx, req := range requests {
+		require.Equal(t, idx+1, req.Attempt.Number)
+		require.Equal(t, requests[0].RequestID, req.RequestID)
+	}
+}
+
+func TestRoundTripper_Breaker(t *testing.T) {
+	fake := transporttest.New().On("*", transporttest.Respond(http.StatusBadGateway, "")).CheckBodiesClosed(t)
+	client := &http.Client{
+		Transport: transport.NewCircuitBreakerTransport(fake,
+			transport.CircuitBreakerOptionLogger(discardLogger),
+			transport.CircuitBreakerOptionBreakerConfig(gobreaker.Settings{
+				ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+				Timeout:     time.Minute,
+			}),
+		),
+	}
+
+	for range 4 {
+		_, err := client.Get("http://example.com/")
+		require.Error(t, err)
+	}
+
+	fake.AssertAttempts(t, "*", 2)
+}
+
+func TestRoundTripper_Delay(t *testing.T) {
+	fake := transporttest.New().On("*", transporttest.Respond(http.StatusOK, "").WithDelay(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+	require.NoError(t, err)
+
+	_, err = fake.RoundTrip(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRoundTripper_BodyLeak(t *testing.T) {
+	fake := transporttest.New().On("*", transporttest.Respond(http.StatusOK, "ok"))
+
+	res, err := (&http.Client{Transport: fake}).Get("http://example.com/leak")
+	require.NoError(t, err)
+
+	rec := &recordingTB{TB: t}
+	require.False(t, fake.AssertBodiesClosed(rec))
+	require.Equal(t, 1, rec.errors)
+	require.Equal(t, []string{"GET http://example.com/leak"}, fake.OpenBodies())
+
+	require.NoError(t, res.Body.Close())
+	require.True(t, fake.AssertBodiesClosed(t))
+}
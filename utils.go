@@ -30,16 +30,63 @@ func CombinePath(method, path string) string {
 	return fmt.Sprintf("%s%s%s", method, ConsCharVerticalBar, path)
 }
 
+// MatchesPath reports whether a METHOD|path string matches pattern, with the pattern syntax of
+// NewMatcher. The catch-all * is left to callers and never matches here.
+//
+// Exact patterns and literal prefixes ending in /* are compared directly. Other patterns are compiled
+// on every call, a regular expression included: callers matching many requests should compile them
+// once with NewMatcher or PathMatches.
 func MatchesPath(pattern, path string) bool {
 	if pattern == ConsCharStar {
 		return false
 	}
 
-	if strings.HasSuffix(pattern, "/*") {
-		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "/*"))
+	method, reqPath, ok := strings.Cut(path, ConsCharVerticalBar)
+	if !ok {
+		method, reqPath = "", path
 	}
 
-	return pattern == path
+	if matched, ok := matchesLiteralPath(pattern, method, reqPath); ok {
+		return matched
+	}
+
+	compiled := compilePathPattern(pattern)
+	return compiled.match(method, reqPath)
+}
+
+// matchesLiteralPath matches [METHOD|]path patterns without wildcards, optionally ending in /*,
+// without compiling them. ok is false for the patterns it cannot decide.
+func matchesLiteralPath(pattern, method, reqPath string) (matched, ok bool) {
+	patternMethod, patternPath, hasMethod := strings.Cut(pattern, ConsCharVerticalBar)
+	if !hasMethod {
+		patternMethod, patternPath = "", pattern
+	}
+
+	prefix, isPrefix := strings.CutSuffix(patternPath, "/*")
+	if isPrefix {
+		patternPath = prefix
+	}
+
+	if patternMethod == ConsCharStar || strings.HasPrefix(patternPath, RegexPathPrefix) ||
+		strings.ContainsAny(patternPath, "*?[\\{") {
+		return false, false
+	}
+
+	if patternMethod != "" && patternMethod != method {
+		return false, true
+	}
+
+	patternPath = strings.TrimPrefix(patternPath, "/")
+	reqPath = strings.TrimPrefix(reqPath, "/")
+	if !isPrefix {
+		return patternPath == reqPath, true
+	}
+
+	if patternPath == "" {
+		return true, true
+	}
+
+	return reqPath == patternPath || (strings.HasPrefix(reqPath, patternPath) && reqPath[len(patternPath)] == '/'), true
 }
 
 func StringLowers(items []string) []string {